@@ -3,28 +3,49 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"path/filepath"
-	"sync"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	descAccess "github.com/Mobo140/auth/pkg/access_v1"
 	descAuth "github.com/Mobo140/auth/pkg/auth_v1"
 	"github.com/Mobo140/chat-cli/cmd/root"
+	"github.com/Mobo140/chat-cli/internal/account"
 	"github.com/Mobo140/chat-cli/internal/clients"
 	authClient "github.com/Mobo140/chat-cli/internal/clients/auth"
 	chatClient "github.com/Mobo140/chat-cli/internal/clients/chat"
 	"github.com/Mobo140/chat-cli/internal/config"
 	"github.com/Mobo140/chat-cli/internal/config/env"
+	"github.com/Mobo140/chat-cli/internal/console"
+	userCredentials "github.com/Mobo140/chat-cli/internal/credentials"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/interceptor"
+	"github.com/Mobo140/chat-cli/internal/lifecycle"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/outbox"
+	"github.com/Mobo140/chat-cli/internal/paths"
+	"github.com/Mobo140/chat-cli/internal/registry"
+	"github.com/Mobo140/chat-cli/internal/render"
+	"github.com/Mobo140/chat-cli/internal/search"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/chat-cli/internal/subscription"
+	"github.com/Mobo140/chat-cli/internal/token"
 	descChat "github.com/Mobo140/chat/pkg/chat_v1"
 	"github.com/Mobo140/platform_common/pkg/closer"
 	"github.com/Mobo140/platform_common/pkg/logger"
-	"github.com/Mobo140/platform_common/pkg/tracing"
 	"github.com/grpc-ecosystem/grpc-opentracing/go/otgrpc"
 	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	jaegerConfig "github.com/uber/jaeger-client-go/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -35,13 +56,35 @@ var (
 	chatCliServiceName = "chat-cli"
 )
 
+// shutdownTimeout сколько ждать остановки каждого фонового компонента при выходе
+const shutdownTimeout = 3 * time.Second
+
+const (
+	// chatKeepaliveTime не чаще, чем gRPC-сервер разрешает по умолчанию
+	chatKeepaliveTime    = 5 * time.Minute
+	chatKeepaliveTimeout = 20 * time.Second
+)
+
 // App структура для хранения конфигурации и клиентов
 type App struct {
-	configPath  string
-	sessionFile string
-	loggerLevel string
-	chatClient  clients.ChatServiceClient
-	authClient  clients.AuthServiceClient
+	configPath       string
+	dirs             *paths.Dirs
+	loggerLevel      string
+	sessionStore     session.Store
+	accountStore     account.Store
+	loginConfig      config.LoginConfig
+	credentialsStore userCredentials.Store
+	tokenManager     token.Manager
+	chatClient       clients.ChatServiceClient
+	authClient       clients.AuthServiceClient
+	chatRegistry     registry.Registry
+	searchIndex      search.Index
+	historyStore     history.Store
+	outboxStore      outbox.Store
+	outboxFlusher    *outbox.Flusher
+	console          *console.Console
+	subscriptions    *subscription.Manager
+	tracer           io.Closer
 }
 
 func main() {
@@ -50,56 +93,135 @@ func main() {
 		log.Fatalf("failed to parse flags: %v", err)
 	}
 
-	ctx := context.Background()
-	currentDir, err := os.Getwd()
+	lc := lifecycle.New(context.Background(), shutdownTimeout)
+
+	dirs, err := paths.New(root.StateDir)
 	if err != nil {
-		log.Fatalf("failed to get current directory: %v", err)
+		log.Fatalf("failed to get state directory: %v", err)
+	}
+
+	if err := dirs.Ensure(); err != nil {
+		log.Fatalf("failed to create state directory: %v", err)
 	}
 
-	sessionFile := filepath.Join(currentDir, ".chat-cli-session")
+	root.HistoryFile = dirs.HistoryFile()
 
 	// Используем root.ConfigPath вместо configPath
-	app, err := NewApp(ctx, root.ConfigPath, sessionFile)
+	app, err := NewApp(lc.Context(), root.ConfigPath, dirs)
 	if err != nil {
 		log.Fatalf("failed to initialize app: %v", err)
 	}
 
-	// Создаем каналы для синхронизации
-	loginDoneChan := make(chan struct{})
-	refreshTokenDoneChan := make(chan struct{})
+	streamConfig := StreamConfig()
+
+	// Фоновые подписки на чаты
+	app.subscriptions = subscription.NewManager(app.chatClient, streamConfig)
 
 	// Инициализация команд
-	root.InitCommands(app.chatClient, app.authClient, app.sessionFile, loginDoneChan)
+	root.InitCommands(root.Deps{
+		ChatClient:       app.chatClient,
+		AuthClient:       app.authClient,
+		SessionStore:     app.sessionStore,
+		AccountStore:     app.accountStore,
+		CredentialsStore: app.credentialsStore,
+		TokenManager:     app.tokenManager,
+		ChatRegistry:     app.chatRegistry,
+		HistoryStore:     app.historyStore,
+		SearchIndex:      app.searchIndex,
+		OutboxStore:      app.outboxStore,
+		OutboxFlusher:    app.outboxFlusher,
+		Subscriptions:    app.subscriptions,
+		Console:          app.console,
+		StreamConfig:     streamConfig,
+		OutputConfig:     OutputConfig(),
+		LoginConfig:      app.loginConfig,
+		RoleConfig:       RoleConfig(),
+	})
 
-	// Запускаем горутины для обновления токенов в отдельной группе
-	var wg sync.WaitGroup
-	wg.Add(2)
+	app.start(lc)
 
-	go func() {
-		defer wg.Done()
-		root.RefreshTokenCron(app.authClient, app.sessionFile, loginDoneChan, refreshTokenDoneChan)
-	}()
+	// REPL обрабатывает Ctrl+C и SIGTERM сам. Команда, запущенная из оболочки,
+	// по сигналу отменяется, и компоненты ниже останавливаются штатно
+	ctx := lc.Context()
+	if !root.Interactive(os.Args[1:]) {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
 
-	go func() {
-		defer wg.Done()
-		root.AccessTokenCron(app.authClient, app.sessionFile, refreshTokenDoneChan)
-	}()
+	// Запускаем REPL в основной горутине, он завершается и при отмене корневого контекста
+	execErr := root.RootCmd.ExecuteContext(ctx)
 
-	// Запускаем REPL в основной горутине
-	if err := root.RootCmd.Execute(); err != nil {
-		log.Fatalf("failed to execute root command: %v", err)
+	if err := lc.Shutdown(); err != nil {
+		log.Printf("shutdown: %v", err)
 	}
 
-	// Ждем завершения горутин при выходе
-	wg.Wait()
+	if execErr != nil {
+		log.Printf("failed to execute root command: %v", execErr)
+		os.Exit(1)
+	}
+}
+
+// start запускает фоновые компоненты. Останавливаются они в обратном порядке:
+// сначала потоки и фоновые задачи, затем соединения, трассировка и логи
+func (a *App) start(lc *lifecycle.Lifecycle) {
+	lc.Start(lifecycle.Component{
+		Name: "log sync",
+		Stop: func(context.Context) error {
+			return logger.Logger().Sync()
+		},
+	})
+
+	lc.Start(lifecycle.Component{
+		Name: "tracer",
+		Stop: func(context.Context) error {
+			return a.tracer.Close()
+		},
+	})
+
+	// gRPC-соединения зарегистрированы в closer при создании клиентов
+	lc.Start(lifecycle.Component{
+		Name: "connections",
+		Stop: func(context.Context) error {
+			closer.CloseAll()
+			return nil
+		},
+	})
+
+	lc.Start(lifecycle.Component{
+		Name: "token maintenance",
+		Run: func(ctx context.Context) error {
+			a.tokenManager.Run(ctx)
+			return nil
+		},
+	})
+
+	// Отправка сообщений, поставленных в очередь без связи с сервером
+	lc.Start(lifecycle.Component{
+		Name: "outbox",
+		Run: func(ctx context.Context) error {
+			a.outboxFlusher.Run(ctx)
+			return nil
+		},
+	})
+
+	lc.Start(lifecycle.Component{
+		Name: "subscriptions",
+		Stop: func(context.Context) error {
+			a.subscriptions.Close()
+			return nil
+		},
+	})
 }
 
 // NewApp создает новый экземпляр приложения
-func NewApp(ctx context.Context, configPath string, sessionFile string) (*App, error) {
+func NewApp(ctx context.Context, configPath string, dirs *paths.Dirs) (*App, error) {
 	app := &App{
-		configPath:  configPath,
-		sessionFile: sessionFile,
-		loggerLevel: root.LogLevel,
+		configPath:   configPath,
+		dirs:         dirs,
+		loggerLevel:  root.LogLevel,
+		accountStore: account.NewFileStore(dirs.AccountFile()),
+		console:      console.New(os.Stdout),
 	}
 
 	err := config.Load(configPath)
@@ -112,16 +234,18 @@ func NewApp(ctx context.Context, configPath string, sessionFile string) (*App, e
 		return nil, fmt.Errorf("failed to init logger: %v", err)
 	}
 
-	err = initTracer()
+	sessionStore, err := initSessionStore(dirs.SessionFile())
 	if err != nil {
-		return nil, fmt.Errorf("failed to init tracer: %v", err)
+		return nil, fmt.Errorf("failed to init session store: %v", err)
 	}
+	app.sessionStore = sessionStore
 
-	chatClient, err := initChatClient(ctx)
+	app.migrateLegacyFiles()
+
+	app.tracer, err = initTracer()
 	if err != nil {
-		return nil, fmt.Errorf("failed to init chat client: %v", err)
+		return nil, fmt.Errorf("failed to init tracer: %v", err)
 	}
-	app.chatClient = chatClient
 
 	authClient, err := initAuthClient(ctx)
 	if err != nil {
@@ -129,20 +253,114 @@ func NewApp(ctx context.Context, configPath string, sessionFile string) (*App, e
 	}
 	app.authClient = authClient
 
+	app.loginConfig = LoginConfig()
+	credentialsFile := app.loginConfig.CredentialsFile()
+	if credentialsFile == "" {
+		credentialsFile = dirs.CredentialsFile()
+	}
+	app.credentialsStore = userCredentials.NewFileStore(credentialsFile)
+	app.tokenManager = token.NewManager(
+		authClient,
+		sessionStore,
+		app.accountStore,
+		app.credentialsStore,
+		TokenConfig(),
+	)
+
+	chatClient, err := initChatClient(ctx, app.tokenManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init chat client: %v", err)
+	}
+	app.chatClient = chatClient
+
+	app.initMessageStores()
+
 	return app, nil
 }
 
+// initMessageStores создаёт локальные хранилища чатов, истории и очереди отправки
+func (a *App) initMessageStores() {
+	a.chatRegistry = registry.NewFileRegistry(a.dirs.ChatsDir())
+	a.searchIndex = search.NewFileIndex(a.dirs.IndexDir())
+	a.historyStore = history.NewFileStore(a.dirs.MessagesDir(), a.searchIndex)
+	a.outboxStore = outbox.NewFileStore(a.dirs.OutboxDir())
+
+	// Доставленные из очереди сообщения попадают в историю так же, как отправленные сразу
+	a.outboxFlusher = outbox.NewFlusher(a.outboxStore, a.chatClient, func(account string, item outbox.Item) {
+		err := a.historyStore.Append(account, model.Message{
+			ChatID:   item.ChatID,
+			Username: item.Username,
+			Text:     item.Text,
+			Time:     time.Now(),
+			Outgoing: true,
+		})
+		if err != nil {
+			logger.Warn("failed to save message to history", zap.String("chat_id", item.ChatID), zap.Error(err))
+		}
+	})
+}
+
 // initLogger инициализирует логгер
 func (a *App) initLogger(_ context.Context) error {
-	logger.Init(getCore(getAtomicLevel(a.loggerLevel)))
+	logger.Init(getCore(getAtomicLevel(a.loggerLevel), a.dirs.LogFile(), a.console))
 	return nil
 }
 
-func getCore(level zap.AtomicLevel) zapcore.Core {
-	stdout := zapcore.AddSync(os.Stdout)
+// migrateLegacyFiles переносит сессии, созданные в текущем каталоге
+// старыми версиями, в каталог состояния и шифрует их, если задана passphrase
+func (a *App) migrateLegacyFiles() {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Warn("failed to get current directory", zap.Error(err))
+		return
+	}
+
+	migrations, err := a.dirs.MigrateLegacy(currentDir)
+	if err != nil {
+		logger.Warn("failed to migrate legacy files", zap.Error(err))
+		return
+	}
+
+	for _, m := range migrations {
+		if m.Err != nil {
+			logger.Warn("failed to migrate legacy file",
+				zap.String("from", m.From),
+				zap.String("to", m.To),
+				zap.Error(m.Err))
+			continue
+		}
+
+		logger.Info("Migrated legacy file", zap.String("from", m.From), zap.String("to", m.To))
+
+		a.migrateLegacySession(m.To)
+	}
+}
+
+// migrateLegacySession перезаписывает незашифрованную сессию старой версии в
+// формате хранилища. Только здесь незашифрованный файл сессии принимается
+func (a *App) migrateLegacySession(path string) {
+	prefix := a.dirs.SessionFile() + "."
+	if !strings.HasPrefix(path, prefix) {
+		return
+	}
+
+	migrator, ok := a.sessionStore.(session.Migrator)
+	if !ok {
+		return
+	}
+
+	if err := migrator.MigratePlaintext(strings.TrimPrefix(path, prefix)); err != nil {
+		logger.Warn("failed to migrate legacy session", zap.String("path", path), zap.Error(err))
+	}
+}
+
+// getCore пишет логи в файл и в консоль; консольный вывод идёт через общий
+// Console, чтобы логи фоновых задач не ломали строку ввода
+func getCore(level zap.AtomicLevel, logFile string, out *console.Console) zapcore.Core {
+	stdout := zapcore.AddSync(out)
 
 	file := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   "logs/app.log",
+		Filename:   logFile,
 		MaxSize:    logsMaxSize, // megabytes
 		MaxBackups: logsMaxBackups,
 		MaxAge:     logsMaxAge, // days
@@ -173,7 +391,80 @@ func getAtomicLevel(logLevel string) zap.AtomicLevel {
 	return zap.NewAtomicLevelAt(level)
 }
 
-func initChatClient(_ context.Context) (clients.ChatServiceClient, error) {
+func initSessionStore(sessionFile string) (session.Store, error) {
+	passphrase := SessionConfig().Passphrase()
+	if passphrase == "" {
+		logger.Warn("CHAT_CLI_SESSION_PASSPHRASE is not set, session tokens are stored unencrypted")
+		return session.NewFileStore(sessionFile, session.NewPlainCodec()), nil
+	}
+
+	codec, err := session.NewEncryptedCodec(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return session.NewFileStore(sessionFile, codec), nil
+}
+
+func SessionConfig() config.SessionConfig {
+	cfg, err := env.NewSessionConfig()
+	if err != nil {
+		log.Fatalf("failed to load session config: %v", err)
+	}
+
+	return cfg
+}
+
+func TokenConfig() config.TokenConfig {
+	cfg, err := env.NewTokenConfig()
+	if err != nil {
+		log.Fatalf("failed to load token config: %v", err)
+	}
+
+	return cfg
+}
+
+func StreamConfig() config.StreamConfig {
+	cfg, err := env.NewStreamConfig()
+	if err != nil {
+		log.Fatalf("failed to load stream config: %v", err)
+	}
+
+	return cfg
+}
+
+func OutputConfig() config.OutputConfig {
+	cfg, err := env.NewOutputConfig()
+	if err != nil {
+		log.Fatalf("failed to load output config: %v", err)
+	}
+
+	if _, err := render.New(cfg.Format(), cfg.Color()); err != nil {
+		log.Fatalf("failed to load output config: %v", err)
+	}
+
+	return cfg
+}
+
+func LoginConfig() config.LoginConfig {
+	cfg, err := env.NewLoginConfig()
+	if err != nil {
+		log.Fatalf("failed to load login config: %v", err)
+	}
+
+	return cfg
+}
+
+func RoleConfig() config.RoleConfig {
+	cfg, err := env.NewRoleConfig()
+	if err != nil {
+		log.Fatalf("failed to load role config: %v", err)
+	}
+
+	return cfg
+}
+
+func initChatClient(_ context.Context, tokenManager token.Manager) (clients.ChatServiceClient, error) {
 	creds, err := credentials.NewClientTLSFromFile("secure/chat.pem", "")
 	if err != nil {
 		log.Fatalf("failed to load TLS keys for chat client: %v", err)
@@ -183,8 +474,18 @@ func initChatClient(_ context.Context) (clients.ChatServiceClient, error) {
 	conn, err := grpc.NewClient(
 		ChatClientConfig().Address(),
 		grpc.WithTransportCredentials(creds),
-		grpc.WithUnaryInterceptor(
+		// Keepalive находит оборванное соединение, даже если в чате никто не пишет
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    chatKeepaliveTime,
+			Timeout: chatKeepaliveTimeout,
+		}),
+		grpc.WithChainUnaryInterceptor(
 			otgrpc.OpenTracingClientInterceptor(opentracing.GlobalTracer()),
+			interceptor.AuthUnaryClientInterceptor(tokenManager),
+		),
+		grpc.WithChainStreamInterceptor(
+			otgrpc.OpenTracingStreamClientInterceptor(opentracing.GlobalTracer()),
+			interceptor.AuthStreamClientInterceptor(tokenManager),
 		),
 	)
 	if err != nil {
@@ -227,7 +528,7 @@ func initAuthClient(_ context.Context) (clients.AuthServiceClient, error) {
 
 	closer.Add(conn.Close)
 
-	return authClient.NewAuthClient(descAuth.NewAuthV1Client(conn)), nil
+	return authClient.NewAuthClient(descAuth.NewAuthV1Client(conn), descAccess.NewAccessV1Client(conn)), nil
 }
 
 func AuthClientConfig() config.AuthClientConfig {
@@ -239,10 +540,19 @@ func AuthClientConfig() config.AuthClientConfig {
 	return cfg
 }
 
-func initTracer() error {
-	tracing.Init(logger.Logger(), chatCliServiceName, JaegerConfig().Address())
+// initTracer настраивает глобальный трейсер, Close отправляет оставшиеся спаны
+func initTracer() (io.Closer, error) {
+	cfg := jaegerConfig.Configuration{
+		Sampler: &jaegerConfig.SamplerConfig{
+			Type:  jaeger.SamplerTypeConst,
+			Param: 1,
+		},
+		Reporter: &jaegerConfig.ReporterConfig{
+			LocalAgentHostPort: JaegerConfig().Address(),
+		},
+	}
 
-	return nil
+	return cfg.InitGlobalTracer(chatCliServiceName)
 }
 
 func JaegerConfig() config.JaegerConfig {
@@ -0,0 +1,178 @@
+package root
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/chat-cli/internal/subscription"
+	"github.com/Mobo140/chat-cli/internal/token"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newAccountsCmd(sessionStore session.Store, accountStore account.Store, tokenManager token.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "accounts",
+		Short: "Manage logged in accounts",
+	}
+
+	cmd.AddCommand(newAccountsListCmd(sessionStore, accountStore))
+	cmd.AddCommand(newAccountsSwitchCmd(sessionStore, accountStore, tokenManager))
+
+	return cmd
+}
+
+func newAccountsListCmd(sessionStore session.Store, accountStore account.Store) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List accounts with saved sessions",
+		Run: func(cmd *cobra.Command, args []string) {
+			usernames, err := sessionStore.List()
+			if err != nil {
+				logger.Error("failed to list sessions", zap.Error(err))
+				return
+			}
+
+			if len(usernames) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No saved sessions. Use 'login' to add an account.")
+				return
+			}
+
+			active, _ := accountStore.Active()
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "\tACCOUNT\tSTATUS\tACCESS TOKEN\tREFRESH TOKEN")
+
+			for _, username := range usernames {
+				marker := ""
+				if username == active {
+					marker = "*"
+				}
+
+				s, err := sessionStore.Load(username)
+				if err != nil {
+					fmt.Fprintf(w, "%s\t%s\t%s\t-\t-\n", marker, username, "unreadable")
+					continue
+				}
+
+				status, accessExpiry, refreshExpiry := sessionStatus(s)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", marker, username, status, accessExpiry, refreshExpiry)
+			}
+
+			w.Flush()
+		},
+	}
+}
+
+func newAccountsSwitchCmd(sessionStore session.Store, accountStore account.Store, tokenManager token.Manager) *cobra.Command {
+	return &cobra.Command{
+		Use:   "switch USERNAME",
+		Short: "Make another logged in account active",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			username := args[0]
+
+			if _, err := sessionStore.Load(username); err != nil {
+				if errors.Is(err, session.ErrNotFound) {
+					logger.Error("no saved session for account, please login first", zap.String("username", username))
+					return
+				}
+				logger.Error("failed to load session", zap.Error(err))
+				return
+			}
+
+			if err := activateAccount(accountStore, tokenManager, username); err != nil {
+				logger.Error("failed to switch account", zap.Error(err))
+				return
+			}
+
+			logger.Info("Switched account", zap.String("username", username))
+		},
+	}
+}
+
+func newLogoutCmd(
+	sessionStore session.Store,
+	accountStore account.Store,
+	tokenManager token.Manager,
+	subscriptions *subscription.Manager,
+) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout [USERNAME]",
+		Short: "Logout and delete the saved session (active account by default)",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			active, _ := accountStore.Active()
+
+			username := active
+			if len(args) > 0 {
+				username = args[0]
+			}
+			if username == "" {
+				logger.Error("no active account to logout")
+				return
+			}
+
+			// Потоки не должны работать от имени аккаунта, из которого вышли
+			for _, info := range subscriptions.UnsubscribeAccount(username) {
+				logger.Info("Unsubscribed", zap.String("username", username), zap.String("chat", info.Label))
+			}
+
+			if username == active {
+				tokenManager.Stop()
+
+				if err := accountStore.ClearActive(); err != nil {
+					logger.Error("failed to clear active account", zap.Error(err))
+					return
+				}
+			}
+
+			err := sessionStore.Delete(username)
+			if errors.Is(err, session.ErrNotFound) {
+				logger.Warn("no saved session for account", zap.String("username", username))
+				return
+			}
+			if err != nil {
+				logger.Error("failed to delete session", zap.Error(err))
+				return
+			}
+
+			logger.Info("Logged out", zap.String("username", username))
+		},
+	}
+}
+
+// sessionStatus описывает состояние токенов сессии для вывода пользователю
+func sessionStatus(s *Session) (status string, accessExpiry string, refreshExpiry string) {
+	now := time.Now()
+
+	refreshClaims, err := token.ParseClaims(s.RefreshToken)
+	if err != nil {
+		return "invalid", "-", "-"
+	}
+
+	refreshExpiry = formatExpiry(refreshClaims.ExpiresAtTime(), now)
+	accessExpiry = "-"
+	if accessClaims, err := token.ParseClaims(s.AccessToken); err == nil {
+		accessExpiry = formatExpiry(accessClaims.ExpiresAtTime(), now)
+	}
+
+	if refreshClaims.Expired(now) {
+		return "expired", accessExpiry, refreshExpiry
+	}
+
+	return "valid", accessExpiry, refreshExpiry
+}
+
+func formatExpiry(t time.Time, now time.Time) string {
+	if t.After(now) {
+		return fmt.Sprintf("%s (in %s)", t.Format(time.DateTime), t.Sub(now).Round(time.Second))
+	}
+
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.DateTime), now.Sub(t).Round(time.Second))
+}
@@ -0,0 +1,148 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/registry"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newChatsCmd(accountStore account.Store, chatRegistry registry.Registry) *cobra.Command {
+	return &cobra.Command{
+		Use:   "chats",
+		Short: "List known chats",
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			chats, err := chatRegistry.List(username)
+			if err != nil {
+				logger.Error("failed to list chats", zap.Error(err))
+				return
+			}
+
+			if len(chats) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No known chats yet.")
+				return
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tALIAS\tMEMBERS\tCREATED\tLAST ACTIVITY")
+
+			for _, chat := range chats {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					chat.ID,
+					valueOrDash(chat.Alias),
+					valueOrDash(strings.Join(chat.Members, ", ")),
+					formatTime(chat.CreatedAt),
+					formatTime(chat.LastActivity))
+			}
+
+			w.Flush()
+		},
+	}
+}
+
+func newChatCmd(accountStore account.Store, chatRegistry registry.Registry) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chat",
+		Short: "Manage the local chat registry",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "alias CHAT ALIAS",
+		Short: "Give a chat a name that can be used instead of its ID",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			if err := chatRegistry.SetAlias(username, args[0], args[1]); err != nil {
+				logger.Error("failed to set chat alias", zap.Error(err))
+				return
+			}
+
+			logger.Info("Chat alias saved", zap.String("chat", args[0]), zap.String("alias", args[1]))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "forget CHAT",
+		Short: "Remove a chat from the local registry",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			if err := chatRegistry.Forget(username, args[0]); err != nil {
+				logger.Error("failed to forget chat", zap.Error(err))
+				return
+			}
+
+			logger.Info("Chat removed from registry", zap.String("chat", args[0]))
+		},
+	})
+
+	return cmd
+}
+
+// resolveChatID принимает значение --chat-id: числовой ID или псевдоним чата
+func resolveChatID(ctx context.Context, accountStore account.Store, chatRegistry registry.Registry, chatRef string) (string, error) {
+	if chatRef == "" {
+		return "", fmt.Errorf("chat id is required")
+	}
+
+	username, err := account.Current(ctx, accountStore)
+	if err != nil {
+		// Без входа псевдонимы недоступны, но числовой ID остаётся валидным
+		return chatRef, nil
+	}
+
+	chatID, err := chatRegistry.Resolve(username, chatRef)
+	if err != nil {
+		return "", fmt.Errorf("unknown chat %q: %w", chatRef, err)
+	}
+
+	return chatID, nil
+}
+
+// touchChat отмечает активность в чате в локальном реестре
+func touchChat(ctx context.Context, accountStore account.Store, chatRegistry registry.Registry, chat model.Chat) {
+	username, err := account.Current(ctx, accountStore)
+	if err != nil {
+		return
+	}
+
+	if chat.LastActivity.IsZero() {
+		chat.LastActivity = time.Now()
+	}
+
+	if err := chatRegistry.Touch(username, chat); err != nil {
+		logger.Warn("failed to update chat registry", zap.String("chat_id", chat.ID), zap.Error(err))
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,140 @@
+package root
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/export"
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/registry"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newExportCmd(accountStore account.Store, chatRegistry registry.Registry, historyStore history.Store) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export --chat-id=ID --format=FORMAT",
+		Short: "Export a chat transcript from local history",
+		Long: `Export locally stored messages of a chat. Formats: ` + strings.Join(export.Formats, ", ") + `.
+JSON, JSON lines and CSV use the fields of the stored messages (time, chat_id,
+username, text). Without --out the transcript is written to standard output.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			chatRef, _ := cmd.Flags().GetString("chat-id")
+			format, _ := cmd.Flags().GetString("format")
+			outPath, _ := cmd.Flags().GetString("out")
+			sinceValue, _ := cmd.Flags().GetString("since")
+			untilValue, _ := cmd.Flags().GetString("until")
+			pseudonymize, _ := cmd.Flags().GetBool("pseudonymize")
+
+			exporter, err := export.New(format)
+			if err != nil {
+				logger.Error("invalid --format", zap.Error(err))
+				return
+			}
+
+			now := time.Now()
+
+			since, err := parseTimeFlag(sinceValue, now)
+			if err != nil {
+				logger.Error("invalid --since", zap.Error(err))
+				return
+			}
+
+			until, err := parseTimeFlag(untilValue, now)
+			if err != nil {
+				logger.Error("invalid --until", zap.Error(err))
+				return
+			}
+
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			chatID, err := resolveChatID(cmd.Context(), accountStore, chatRegistry, chatRef)
+			if err != nil {
+				logger.Error("failed to resolve chat", zap.Error(err))
+				return
+			}
+
+			page, err := historyStore.Query(username, history.Query{
+				ChatID: chatID,
+				Since:  since,
+				Until:  until,
+			})
+			if err != nil {
+				logger.Error("failed to read history", zap.Error(err))
+				return
+			}
+
+			messages := page.Messages
+			if pseudonymize {
+				messages = export.Pseudonymize(messages)
+			}
+
+			title := "Chat " + chatID
+			if label, ok := chatLabels(username, chatRegistry)[chatID]; ok && !pseudonymize {
+				title = "Chat " + label
+			}
+
+			transcript := export.Transcript{
+				Title:      title,
+				ExportedAt: now,
+				Messages:   messages,
+			}
+
+			if err := writeExport(cmd.OutOrStdout(), outPath, exporter, transcript); err != nil {
+				logger.Error("failed to export chat", zap.Error(err))
+				return
+			}
+
+			if outPath != "" {
+				logger.Info("Chat exported",
+					zap.String("chat_id", chatID),
+					zap.Int("messages", len(messages)),
+					zap.String("file", outPath))
+			}
+		},
+	}
+
+	cmd.Flags().String("chat-id", "", "Chat ID or alias")
+	cmd.Flags().String("format", export.FormatJSON, "Output format: "+strings.Join(export.Formats, ", "))
+	cmd.Flags().String("out", "", "Output file (default: standard output)")
+	cmd.Flags().String("since", "", "Only messages newer than a duration or date")
+	cmd.Flags().String("until", "", "Only messages older than a duration or date")
+	cmd.Flags().Bool("pseudonymize", false, "Replace usernames with user1, user2... for sharing")
+	cmd.MarkFlagRequired("chat-id")
+
+	return cmd
+}
+
+// writeExport пишет в файл целиком или не пишет вовсе
+func writeExport(stdout io.Writer, path string, exporter export.Exporter, t export.Transcript) error {
+	if path == "" {
+		out := bufio.NewWriter(stdout)
+		if err := exporter.Export(out, t); err != nil {
+			return err
+		}
+
+		return out.Flush()
+	}
+
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf, t); err != nil {
+		return err
+	}
+
+	if err := fileutil.WriteFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
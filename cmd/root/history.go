@@ -0,0 +1,144 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/registry"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const defaultHistoryLimit = 50
+
+func newHistoryCmd(accountStore account.Store, chatRegistry registry.Registry, historyStore history.Store) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history --chat-id=ID",
+		Short: "Show locally stored chat messages",
+		Long: `Show messages sent and received by this client, newest page first.
+--since and --until accept a duration (2h, 30m) or a date (2006-01-02, "2006-01-02 15:04").`,
+		Run: func(cmd *cobra.Command, args []string) {
+			chatRef, _ := cmd.Flags().GetString("chat-id")
+			sinceValue, _ := cmd.Flags().GetString("since")
+			untilValue, _ := cmd.Flags().GetString("until")
+			from, _ := cmd.Flags().GetString("from")
+			limit, _ := cmd.Flags().GetInt("limit")
+			page, _ := cmd.Flags().GetInt("page")
+
+			if limit <= 0 || page <= 0 {
+				logger.Error("--limit and --page must be positive")
+				return
+			}
+
+			now := time.Now()
+
+			since, err := parseTimeFlag(sinceValue, now)
+			if err != nil {
+				logger.Error("invalid --since", zap.Error(err))
+				return
+			}
+
+			until, err := parseTimeFlag(untilValue, now)
+			if err != nil {
+				logger.Error("invalid --until", zap.Error(err))
+				return
+			}
+
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			chatID, err := resolveChatID(cmd.Context(), accountStore, chatRegistry, chatRef)
+			if err != nil {
+				logger.Error("failed to resolve chat", zap.Error(err))
+				return
+			}
+
+			result, err := historyStore.Query(username, history.Query{
+				ChatID: chatID,
+				Since:  since,
+				Until:  until,
+				From:   from,
+				Limit:  limit,
+				Offset: (page - 1) * limit,
+			})
+			if err != nil {
+				logger.Error("failed to read history", zap.Error(err))
+				return
+			}
+
+			out := cmd.OutOrStdout()
+
+			if len(result.Messages) == 0 {
+				fmt.Fprintln(out, "No messages found.")
+				return
+			}
+
+			printMessages(out, result.Messages)
+
+			if result.More {
+				fmt.Fprintf(out, "-- older messages: history --chat-id=%s --page=%d\n", chatRef, page+1)
+			}
+		},
+	}
+
+	cmd.Flags().String("chat-id", "", "Chat ID or alias")
+	cmd.Flags().String("since", "", "Only messages newer than a duration or date")
+	cmd.Flags().String("until", "", "Only messages older than a duration or date")
+	cmd.Flags().String("from", "", "Only messages from this user")
+	cmd.Flags().Int("limit", defaultHistoryLimit, "Messages per page")
+	cmd.Flags().Int("page", 1, "Page number, 1 is the newest")
+	cmd.MarkFlagRequired("chat-id")
+
+	return cmd
+}
+
+// recordMessage сохраняет сообщение в локальной истории активного аккаунта
+func recordMessage(ctx context.Context, accountStore account.Store, historyStore history.Store, msg model.Message) {
+	username, err := account.Current(ctx, accountStore)
+	if err != nil {
+		return
+	}
+
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	msg.Outgoing = true
+
+	if err := historyStore.Append(username, msg); err != nil {
+		logger.Warn("failed to save message to history", zap.String("chat_id", msg.ChatID), zap.Error(err))
+	}
+}
+
+func printMessages(out io.Writer, messages []model.Message) {
+	for _, msg := range messages {
+		fmt.Fprintf(out, "%s [%s]: %s\n", msg.Time.Local().Format(time.DateTime), msg.Username, msg.Text)
+	}
+}
+
+// parseTimeFlag принимает длительность от текущего момента (2h) или дату в локальном времени
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q is neither a duration nor a date", value)
+}
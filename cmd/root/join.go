@@ -0,0 +1,331 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/render"
+	"github.com/Mobo140/chat-cli/internal/stream"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	// roomHistoryDefault сколько сообщений /history показывает по умолчанию
+	roomHistoryDefault = 20
+	meCommand          = render.ActionPrefix
+)
+
+func newJoinCmd(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "join CHAT",
+		Short: "Join a chat and talk interactively",
+		Long: `Join a chat by ID or alias: incoming messages are printed above the prompt
+and every entered line is sent to the chat. Lines starting with / are commands:
+  /leave           leave the chat (also Ctrl+D)
+  /who             show chat members
+  /me ACTION       send an action, e.g. /me waves
+  /history [N]     show the last N stored messages of this chat
+  /help            show this help
+Start a message with // to send a line beginning with /.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if repl == nil {
+				logger.Error("join is only available in interactive mode")
+				return
+			}
+
+			username, err := account.Current(cmd.Context(), deps.AccountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			chatID, err := resolveChatID(cmd.Context(), deps.AccountStore, deps.ChatRegistry, args[0])
+			if err != nil {
+				logger.Error("failed to resolve chat", zap.Error(err))
+				return
+			}
+
+			renderer, err := outputRenderer(cmd, deps.OutputConfig)
+			if err != nil {
+				logger.Error("invalid output format", zap.Error(err))
+				return
+			}
+
+			room := &chatRoom{
+				deps:     deps,
+				rl:       repl,
+				renderer: renderer,
+				chatID:   chatID,
+				title:    chatID,
+				username: username,
+				members:  map[string]struct{}{username: {}},
+			}
+			room.loadKnownChat()
+
+			room.run(cmd.Context())
+		},
+	}
+
+	addFormatFlag(cmd)
+
+	return cmd
+}
+
+// chatRoom интерактивный режим чата: поток входящих сообщений и ввод в одном терминале
+type chatRoom struct {
+	deps     Deps
+	rl       *readline.Instance
+	renderer render.Renderer
+
+	chatID   string
+	title    string
+	username string
+
+	mu      sync.Mutex
+	members map[string]struct{}
+}
+
+// loadKnownChat берёт псевдоним и участников чата из локального реестра
+func (r *chatRoom) loadKnownChat() {
+	chats, err := r.deps.ChatRegistry.List(r.username)
+	if err != nil {
+		logger.Debug("failed to list chats", zap.Error(err))
+		return
+	}
+
+	for _, c := range chats {
+		if c.ID != r.chatID {
+			continue
+		}
+
+		if c.Alias != "" {
+			r.title = c.Alias
+		}
+		for _, member := range c.Members {
+			r.members[member] = struct{}{}
+		}
+	}
+}
+
+func (r *chatRoom) run(ctx context.Context) {
+	prompt := r.rl.Config.Prompt
+	r.rl.SetPrompt(fmt.Sprintf("[%s] %s> ", r.title, r.username))
+	defer r.rl.SetPrompt(prompt)
+
+	touchChat(ctx, r.deps.AccountStore, r.deps.ChatRegistry, model.Chat{
+		ID:      r.chatID,
+		Members: []string{r.username},
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		supervisor := stream.NewSupervisor(r.deps.ChatClient, r.deps.StreamConfig, r.chatID, r.username, r.receive, r.streamEvent)
+		if err := supervisor.Run(ctx); err != nil {
+			r.printf("*** Type /leave to exit.\n")
+		}
+	}()
+
+	r.printf("*** Joined chat %s as %s. Type /help for commands, /leave to exit.\n", r.title, r.username)
+
+	for {
+		line, err := r.rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			if line == "" {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			break
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if !r.handle(ctx, line) {
+			break
+		}
+	}
+
+	r.printf("*** Left chat %s\n", r.title)
+}
+
+// streamEvent показывает разрывы и восстановление потока чата
+func (r *chatRoom) streamEvent(e stream.Event) {
+	if e.State == stream.StateConnecting || (e.State == stream.StateConnected && e.Attempt == 0) {
+		return
+	}
+	if e.State == stream.StateStopped && e.Err == nil {
+		return
+	}
+
+	if err := r.renderer.Status(printer, r.title, stream.Describe(e)); err != nil {
+		logger.Debug("failed to print stream status", zap.Error(err))
+	}
+}
+
+// handle обрабатывает строку ввода и возвращает false, если пора покинуть чат
+func (r *chatRoom) handle(ctx context.Context, line string) bool {
+	if !strings.HasPrefix(line, "/") || strings.HasPrefix(line, "//") {
+		r.send(ctx, strings.TrimPrefix(line, "/"))
+		return true
+	}
+
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "/leave", "/quit", "/exit":
+		return false
+	case "/who":
+		r.printf("*** Members: %s\n", strings.Join(r.memberList(), ", "))
+	case "/me":
+		if arg == "" {
+			r.printf("*** Usage: /me ACTION\n")
+			break
+		}
+		r.send(ctx, meCommand+arg)
+	case "/history":
+		r.printHistory(arg)
+	case "/help":
+		r.printf("*** Commands: /leave, /who, /me ACTION, /history [N], /help\n")
+	default:
+		r.printf("*** Unknown command %s, type /help\n", command)
+	}
+
+	return true
+}
+
+func (r *chatRoom) send(ctx context.Context, text string) {
+	queued, err := deliverMessage(ctx, r.deps.ChatClient, r.deps.OutboxStore, r.deps.OutboxFlusher, r.username, &chat.Message{
+		ChatID:   r.chatID,
+		Text:     text,
+		Username: r.username,
+	})
+	if err != nil {
+		r.printf("*** Message not sent: %v\n", err)
+		return
+	}
+	if queued != "" {
+		r.printf("*** Server unreachable, message queued as %s and will be sent automatically\n", queued)
+		return
+	}
+
+	r.record(model.Message{
+		ChatID:   r.chatID,
+		Username: r.username,
+		Text:     text,
+		Time:     time.Now(),
+		Outgoing: true,
+	})
+
+	touchChat(ctx, r.deps.AccountStore, r.deps.ChatRegistry, model.Chat{
+		ID:      r.chatID,
+		Members: []string{r.username},
+	})
+}
+
+// receive вызывается потоком чата для каждого входящего сообщения
+func (r *chatRoom) receive(msg *chat.Message) {
+	m := model.Message{
+		ChatID:   msg.ChatID,
+		Username: msg.Username,
+		Text:     msg.Text,
+		Time:     msg.Time,
+	}
+	// Время сервера неизвестно, используем время получения
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	r.members[m.Username] = struct{}{}
+	r.mu.Unlock()
+
+	r.record(m)
+	r.show(m)
+}
+
+func (r *chatRoom) record(m model.Message) {
+	if err := r.deps.HistoryStore.Append(r.username, m); err != nil {
+		logger.Warn("failed to save message to history", zap.String("chat_id", m.ChatID), zap.Error(err))
+	}
+}
+
+func (r *chatRoom) printHistory(arg string) {
+	limit := roomHistoryDefault
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			r.printf("*** Usage: /history [N]\n")
+			return
+		}
+		limit = n
+	}
+
+	page, err := r.deps.HistoryStore.Query(r.username, history.Query{
+		ChatID: r.chatID,
+		Limit:  limit,
+	})
+	if err != nil {
+		r.printf("*** Failed to read history: %v\n", err)
+		return
+	}
+
+	if len(page.Messages) == 0 {
+		r.printf("*** No messages yet\n")
+		return
+	}
+
+	for _, m := range page.Messages {
+		r.show(m)
+	}
+}
+
+// show выводит сообщение в выбранном формате, как connect-chat и subscribe
+func (r *chatRoom) show(m model.Message) {
+	if err := r.renderer.Message(printer, r.title, m); err != nil {
+		logger.Debug("failed to print message", zap.Error(err))
+	}
+}
+
+func (r *chatRoom) memberList() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := make([]string, 0, len(r.members))
+	for member := range r.members {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	return members
+}
+
+// printf печатает над строкой ввода, не затирая набираемый текст
+func (r *chatRoom) printf(format string, args ...any) {
+	printer.Printf(format, args...)
+}
@@ -0,0 +1,68 @@
+package root
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/console"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/render"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+// storedTimes возвращает время сохранённых в истории сообщений чата
+func storedTimes(t *testing.T, store history.Store, username, chatID string) []time.Time {
+	t.Helper()
+
+	page, err := store.Query(username, history.Query{ChatID: chatID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	times := make([]time.Time, 0, len(page.Messages))
+	for _, msg := range page.Messages {
+		times = append(times, msg.Time)
+	}
+
+	return times
+}
+
+func TestChatRoomReceiveKeepsServerTime(t *testing.T) {
+	logger.Init(zapcore.NewNopCore())
+
+	renderer, err := render.New(render.FormatPlain, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printer = console.New(&bytes.Buffer{})
+
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "messages"), nil)
+	r := &chatRoom{
+		deps:     Deps{HistoryStore: store},
+		renderer: renderer,
+		chatID:   "1",
+		username: "bob",
+		members:  make(map[string]struct{}),
+	}
+
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.receive(&chat.Message{ChatID: "1", Username: "alice", Text: "old", Time: sent})
+
+	before := time.Now()
+	r.receive(&chat.Message{ChatID: "1", Username: "alice", Text: "new"})
+
+	times := storedTimes(t, store, "bob", "1")
+	if len(times) != 2 {
+		t.Fatalf("stored %d messages, want 2", len(times))
+	}
+	if !times[0].Equal(sent) {
+		t.Errorf("message with server time stored at %v, want %v", times[0], sent)
+	}
+	if times[1].Before(before) {
+		t.Errorf("message without server time stored at %v, want receive time", times[1])
+	}
+}
@@ -0,0 +1,152 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"github.com/Mobo140/chat-cli/internal/credentials"
+	"github.com/Mobo140/chat-cli/internal/token"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newLoginCmd(
+	accountStore account.Store,
+	credentialsStore credentials.Store,
+	tokenManager token.Manager,
+	loginConfig config.LoginConfig,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Login to the chat",
+		Long: `Login to the chat with a password, a saved credentials profile or an existing refresh token.
+Without --username the refresh token from CHAT_CLI_REFRESH_TOKEN is used if set.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			username, _ := cmd.Flags().GetString("username")
+			profileName, _ := cmd.Flags().GetString("profile")
+			refreshTokenFile, _ := cmd.Flags().GetString("refresh-token-file")
+
+			var (
+				creds loginCredentials
+				err   error
+			)
+			switch {
+			case refreshTokenFile != "":
+				creds, err = refreshTokenFileCredentials(refreshTokenFile)
+			case profileName != "":
+				creds, err = profileCredentials(credentialsStore, profileName)
+			case username != "":
+				creds, err = passwordCredentials(cmd, username)
+			case loginConfig.RefreshToken() != "":
+				creds = loginCredentials{refreshToken: loginConfig.RefreshToken()}
+			default:
+				err = errors.New("username is required")
+			}
+			if err != nil {
+				logger.Error("failed to login", zap.Error(err))
+				return
+			}
+
+			// Таймаут запроса отсчитывается после ввода пароля
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			username, err = creds.login(ctx, tokenManager)
+			if err != nil {
+				logger.Error("failed to login", zap.Error(err))
+				return
+			}
+
+			if err := activateAccount(accountStore, tokenManager, username); err != nil {
+				logger.Error("failed to set active account", zap.Error(err))
+				return
+			}
+
+			logger.Info("Logged in successfully", zap.String("username", username))
+		},
+	}
+
+	cmd.Flags().String("username", "", "Username for login")
+	cmd.Flags().String("password", "", "Password for login (prompted with hidden input if omitted)")
+	cmd.Flags().Bool("password-stdin", false, "Read the password from the next line of standard input")
+	cmd.Flags().String("profile", "", "Login with a profile from the credentials file (CHAT_CLI_CREDENTIALS_FILE)")
+	cmd.Flags().String("refresh-token-file", "", "Login with a refresh token read from file")
+
+	return cmd
+}
+
+// loginCredentials данные для входа: пароль или готовый refresh token
+type loginCredentials struct {
+	username     string
+	password     string
+	refreshToken string
+}
+
+// login выполняет вход и возвращает имя вошедшего пользователя
+func (c loginCredentials) login(ctx context.Context, tokenManager token.Manager) (string, error) {
+	if c.refreshToken != "" {
+		return tokenManager.Import(ctx, c.refreshToken)
+	}
+
+	return c.username, tokenManager.Login(ctx, c.username, c.password)
+}
+
+func passwordCredentials(cmd *cobra.Command, username string) (loginCredentials, error) {
+	password, _ := cmd.Flags().GetString("password")
+	passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
+
+	var err error
+	switch {
+	case passwordStdin:
+		password, err = readPasswordStdin()
+	case password == "":
+		password, err = readPassword("Password: ")
+	}
+	if err != nil {
+		return loginCredentials{}, fmt.Errorf("failed to read password: %w", err)
+	}
+	if password == "" {
+		return loginCredentials{}, errors.New("password is empty")
+	}
+
+	return loginCredentials{username: username, password: password}, nil
+}
+
+func profileCredentials(credentialsStore credentials.Store, name string) (loginCredentials, error) {
+	profile, err := credentialsStore.Profile(name)
+	if err != nil {
+		return loginCredentials{}, fmt.Errorf("profile %q: %w", name, err)
+	}
+
+	password, err := profile.Password()
+	if err != nil {
+		return loginCredentials{}, err
+	}
+
+	return loginCredentials{username: profile.Username, password: password}, nil
+}
+
+func refreshTokenFileCredentials(path string) (loginCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return loginCredentials{}, fmt.Errorf("failed to read refresh token file: %w", err)
+	}
+
+	return loginCredentials{refreshToken: strings.TrimSpace(string(data))}, nil
+}
+
+func activateAccount(accountStore account.Store, tokenManager token.Manager, username string) error {
+	if err := accountStore.SetActive(username); err != nil {
+		return err
+	}
+
+	tokenManager.Start(username)
+
+	return nil
+}
@@ -1,17 +1,7 @@
 package root
 
-import "time"
+import "github.com/Mobo140/chat-cli/internal/model"
 
-type Session struct {
-	RefreshToken string    `json:"refresh_token"`
-	AccessToken  string    `json:"access_token"`
-	Username     string    `json:"username"`
-	Messages     []Message `json:"messages"`
-}
+type Session = model.Session
 
-type Message struct {
-	ChatID   string    `json:"chat_id"`
-	Username string    `json:"username"`
-	Text     string    `json:"text"`
-	Time     time.Time `json:"time"`
-}
+type Message = model.Message
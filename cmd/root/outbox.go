@@ -0,0 +1,256 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/clients"
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/outbox"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const outboxTextWidth = 40
+
+func newOutboxCmd(accountStore account.Store, outboxStore outbox.Store, flusher *outbox.Flusher) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outbox",
+		Short: "Messages waiting to be sent",
+		Long: `Messages that could not be sent because the chat server was unreachable
+are queued and sent in the background once it is available again.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List queued messages",
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			items, err := outboxStore.List(username)
+			if err != nil {
+				logger.Error("failed to read outbox", zap.Error(err))
+				return
+			}
+
+			if len(items) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Outbox is empty.")
+				return
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tCHAT\tQUEUED\tATTEMPTS\tSTATUS\tTEXT\tLAST ERROR")
+
+			for _, item := range items {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+					item.ID,
+					item.ChatID,
+					formatTime(item.CreatedAt),
+					item.Attempts,
+					item.Status,
+					truncate(item.Text, outboxTextWidth),
+					valueOrDash(item.LastError))
+			}
+
+			w.Flush()
+		},
+	})
+
+	dropCmd := &cobra.Command{
+		Use:   "drop ID...",
+		Short: "Cancel queued messages",
+		Run: func(cmd *cobra.Command, args []string) {
+			all, _ := cmd.Flags().GetBool("all")
+
+			if len(args) == 0 && !all {
+				logger.Error("specify message IDs or --all")
+				return
+			}
+
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			ids := args
+			if all {
+				items, err := outboxStore.List(username)
+				if err != nil {
+					logger.Error("failed to read outbox", zap.Error(err))
+					return
+				}
+
+				ids = make([]string, 0, len(items))
+				for _, item := range items {
+					ids = append(ids, item.ID)
+				}
+			}
+
+			if len(ids) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Outbox is empty.")
+				return
+			}
+
+			if err := outboxStore.Remove(username, ids...); err != nil {
+				logger.Error("failed to drop messages", zap.Error(err))
+				return
+			}
+
+			logger.Info("Messages dropped from outbox", zap.Strings("ids", ids))
+		},
+	}
+	dropCmd.Flags().Bool("all", false, "Drop all queued messages")
+
+	cmd.AddCommand(dropCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "retry ID...",
+		Short: "Send failed messages again",
+		Long: `Send failed messages again. A message that timed out may already have been
+delivered, check the chat first to avoid sending it twice.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			for _, id := range args {
+				err := outboxStore.Update(username, id, func(item *outbox.Item) {
+					item.Status = outbox.StatusPending
+				})
+				if err != nil {
+					logger.Error("failed to retry message", zap.String("id", id), zap.Error(err))
+					return
+				}
+			}
+
+			flusher.Notify()
+
+			logger.Info("Messages queued for sending", zap.Strings("ids", args))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "flush",
+		Short: "Try to send queued messages now",
+		Run: func(cmd *cobra.Command, args []string) {
+			flusher.Notify()
+		},
+	})
+
+	return cmd
+}
+
+// deliverMessage отправляет сообщение, а если сервер недоступен или в чате уже
+// есть неотправленные сообщения, ставит его в очередь. queued содержит ID
+// сообщения в очереди
+func deliverMessage(
+	ctx context.Context,
+	chatClient clients.ChatServiceClient,
+	outboxStore outbox.Store,
+	flusher *outbox.Flusher,
+	username string,
+	msg *chat.Message,
+) (queued string, err error) {
+	// Новое сообщение не должно обогнать ожидающие отправки
+	items, err := outboxStore.List(username)
+	if err != nil {
+		logger.Warn("failed to read outbox", zap.Error(err))
+	}
+
+	for _, item := range items {
+		if item.ChatID == msg.ChatID && item.Status == outbox.StatusPending {
+			return queueMessage(outboxStore, flusher, username, msg)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = chatClient.SendMessage(ctx, msg)
+	if err == nil {
+		return "", nil
+	}
+	if outbox.MaybeDelivered(err) {
+		return "", holdMessage(outboxStore, username, msg, err)
+	}
+	if !outbox.IsRetryable(err) {
+		return "", err
+	}
+
+	return queueMessage(outboxStore, flusher, username, msg)
+}
+
+func queueMessage(outboxStore outbox.Store, flusher *outbox.Flusher, username string, msg *chat.Message) (string, error) {
+	item := outbox.NewItem(msg.ChatID, msg.Username, msg.Text)
+
+	if err := outboxStore.Add(username, item); err != nil {
+		return "", fmt.Errorf("failed to queue message: %w", err)
+	}
+
+	flusher.Notify()
+
+	return item.ID, nil
+}
+
+// holdMessage сохраняет сообщение, которое сервер мог принять, не дождавшись
+// ответа. Автоматически оно не отправляется, чтобы не появилось дубликата
+func holdMessage(outboxStore outbox.Store, username string, msg *chat.Message, sendErr error) error {
+	item := outbox.NewItem(msg.ChatID, msg.Username, msg.Text)
+	item.Attempts = 1
+	item.Status = outbox.StatusFailed
+	item.LastError = sendErr.Error()
+
+	if err := outboxStore.Add(username, item); err != nil {
+		return fmt.Errorf("%w, the message may have been delivered", sendErr)
+	}
+
+	return fmt.Errorf("%w, the message may have been delivered. If it did not arrive, resend it with 'outbox retry %s'",
+		sendErr, item.ID)
+}
+
+// warnPendingOutbox предупреждает о неотправленных сообщениях при выходе
+func warnPendingOutbox(outboxStore outbox.Store) {
+	accounts, err := outboxStore.Accounts()
+	if err != nil {
+		logger.Warn("failed to read outbox", zap.Error(err))
+		return
+	}
+
+	pending := 0
+	for _, acc := range accounts {
+		items, err := outboxStore.List(acc)
+		if err != nil {
+			logger.Warn("failed to read outbox", zap.String("account", acc), zap.Error(err))
+			continue
+		}
+		pending += outbox.Pending(items)
+	}
+
+	if pending > 0 {
+		printer.Printf("%d queued message(s) not sent yet, they will be sent the next time chat-cli runs. See 'outbox list'.", pending)
+	}
+}
+
+func truncate(s string, width int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,135 @@
+package root
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/Mobo140/chat-cli/internal/shellwords"
+	"github.com/chzyer/readline"
+)
+
+const (
+	historyFilePerm = 0o600
+
+	passwordFlag = "--password"
+)
+
+// repl экземпляр readline текущего REPL, nil вне интерактивного режима
+var repl *readline.Instance
+
+// readPassword запрашивает пароль с отключённым эхом
+func readPassword(prompt string) (string, error) {
+	if repl == nil {
+		return "", errors.New("password prompt is only available in interactive mode, use --password-stdin")
+	}
+
+	password, err := repl.ReadPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	return string(password), nil
+}
+
+// readPasswordStdin читает пароль из следующей строки ввода, не сохраняя её в истории
+func readPasswordStdin() (string, error) {
+	if repl != nil {
+		return readPassword("")
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// scrubPassword убирает флаг --password со значением из ввода перед записью в историю.
+// Ввод разбирается так же, как в REPL, поэтому пароль в кавычках удаляется целиком.
+// Пустая строка означает, что ввод сохранять нельзя
+func scrubPassword(input string) string {
+	if !strings.Contains(input, passwordFlag) {
+		return input
+	}
+
+	args, err := shellwords.Split(input)
+	if err != nil {
+		// Границы пароля неизвестны, поэтому строка не сохраняется совсем
+		return ""
+	}
+
+	kept := args[:0]
+	found := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == passwordFlag:
+			found = true
+			i++ // значение флага
+		case strings.HasPrefix(args[i], passwordFlag+"="):
+			found = true
+		default:
+			kept = append(kept, args[i])
+		}
+	}
+	if !found {
+		return input
+	}
+
+	scrubbed := shellwords.Join(kept)
+	if strings.Contains(scrubbed, "\n") {
+		// Запись истории занимает одну строку файла
+		return ""
+	}
+
+	return scrubbed
+}
+
+// historyEntries строки ввода для истории. Ввод с паролем сохраняется одной
+// строкой без него, даже если пароль занимал несколько строк
+func historyEntries(input []string) []string {
+	joined := strings.Join(input, "\n")
+
+	scrubbed := scrubPassword(joined)
+	if scrubbed == joined {
+		return input
+	}
+	if scrubbed == "" {
+		return nil
+	}
+
+	return []string{scrubbed}
+}
+
+// scrubHistoryFile удаляет пароли, попавшие в файл истории раньше
+func scrubHistoryFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	kept := lines[:0]
+	changed := false
+	for _, line := range lines {
+		scrubbed := scrubPassword(line)
+		if scrubbed != line {
+			changed = true
+			if scrubbed == "" {
+				continue
+			}
+		}
+		kept = append(kept, scrubbed)
+	}
+	if !changed {
+		return os.Chmod(path, historyFilePerm)
+	}
+	lines = kept
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), historyFilePerm)
+}
@@ -0,0 +1,68 @@
+package root
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScrubPasswordRemovesQuotedValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`login --username bob --password "my secret"`, `login --username bob`},
+		{`login --password 'my secret' --username bob`, `login --username bob`},
+		{`login --password="my secret"`, `login`},
+		{`login --password my\ secret`, `login`},
+		{`send-message "how to use --password"`, `send-message "how to use --password"`},
+		{`login --password "unterminated`, ``},
+	}
+
+	for _, tt := range tests {
+		got := scrubPassword(tt.input)
+		if got != tt.want {
+			t.Errorf("scrubPassword(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if strings.Contains(got, "secret") && !strings.Contains(tt.want, "secret") {
+			t.Errorf("scrubPassword(%q) leaks the password: %q", tt.input, got)
+		}
+	}
+}
+
+func TestHistoryEntriesPasswordAcrossLines(t *testing.T) {
+	got := historyEntries([]string{`login --password "my`, `secret" --username bob`})
+	if want := []string{"login --username bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("historyEntries = %q, want %q", got, want)
+	}
+
+	input := []string{`send-message "first`, `second"`}
+	if got := historyEntries(input); !reflect.DeepEqual(got, input) {
+		t.Fatalf("historyEntries = %q, want input unchanged", got)
+	}
+}
+
+func TestScrubHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	data := "chats\nlogin --password \"my secret\" --username bob\nlogin --password \"my\nhistory\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scrubHistoryFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "secret") {
+		t.Fatalf("history still contains the password:\n%s", got)
+	}
+	if want := "chats\nlogin --username bob\nhistory\n"; string(got) != want {
+		t.Fatalf("unexpected history:\n%s", got)
+	}
+}
@@ -0,0 +1,532 @@
+package root
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/console"
+	"github.com/Mobo140/chat-cli/internal/credentials"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/outbox"
+	"github.com/Mobo140/chat-cli/internal/registry"
+	"github.com/Mobo140/chat-cli/internal/search"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/chat-cli/internal/subscription"
+	"github.com/Mobo140/chat-cli/internal/token"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeChatClient struct {
+	mu   sync.Mutex
+	sent []chat.Message
+	// sendErr ошибка, которую возвращает SendMessage
+	sendErr error
+}
+
+func (c *fakeChatClient) Create(context.Context, []string) (string, error) {
+	return "1", nil
+}
+
+func (c *fakeChatClient) Delete(context.Context, string) error {
+	return nil
+}
+
+func (c *fakeChatClient) SendMessage(_ context.Context, msg *chat.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	c.sent = append(c.sent, *msg)
+
+	return nil
+}
+
+func (c *fakeChatClient) failSends(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sendErr = err
+}
+
+func (c *fakeChatClient) ConnectChat(ctx context.Context, _, _ string, _ func(*chat.Message)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *fakeChatClient) messages() []chat.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]chat.Message(nil), c.sent...)
+}
+
+var errUnavailable = errors.New("auth server is unavailable")
+
+// fakeToken собирает неподписанный JWT, который живёт час
+func fakeToken(username, role string) string {
+	payload, _ := json.Marshal(map[string]any{
+		"username": username,
+		"role":     role,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+// fakeAuthClient пускает только пользователей из passwords, для остальных
+// сервер недоступен
+type fakeAuthClient struct {
+	passwords map[string]string
+}
+
+func (c fakeAuthClient) Login(_ context.Context, username, password string) (string, error) {
+	if want, ok := c.passwords[username]; !ok || want != password {
+		return "", errUnavailable
+	}
+
+	return fakeToken(username, ""), nil
+}
+
+func (c fakeAuthClient) GetAccessToken(_ context.Context, refreshToken string) (string, error) {
+	claims, err := token.ParseClaims(refreshToken)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := c.passwords[claims.Username]; !ok {
+		return "", errUnavailable
+	}
+
+	return fakeToken(claims.Username, ""), nil
+}
+
+func (fakeAuthClient) GetRefreshToken(context.Context, string) (string, error) {
+	return "", errUnavailable
+}
+
+func (fakeAuthClient) CheckAccess(context.Context, string, string) error {
+	return errUnavailable
+}
+
+// testConfig настройки всех видов со значениями по умолчанию
+type testConfig struct{}
+
+func (testConfig) AccessTokenMargin() time.Duration  { return time.Minute }
+func (testConfig) RefreshTokenMargin() time.Duration { return time.Hour }
+func (testConfig) ReconnectMinDelay() time.Duration  { return 10 * time.Millisecond }
+func (testConfig) ReconnectMaxDelay() time.Duration  { return 100 * time.Millisecond }
+func (testConfig) StallTimeout() time.Duration       { return 0 }
+func (testConfig) Format() string                    { return "plain" }
+func (testConfig) Color() bool                       { return false }
+func (testConfig) RefreshToken() string              { return "" }
+func (testConfig) CredentialsFile() string           { return "" }
+func (testConfig) RoleName(value string) string      { return value }
+
+type testSession struct {
+	chat     *fakeChatClient
+	auth     fakeAuthClient
+	out      *bytes.Buffer
+	logs     *observer.ObservedLogs
+	sessions session.Store
+	accounts account.Store
+	// last дерево команд, на котором выполнялась последняя строка
+	last *cobra.Command
+}
+
+// newTestSession готовит зависимости REPL на временных файлах с вошедшими bob и alice
+func newTestSession(t *testing.T) *testSession {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.Init(core)
+
+	dir := t.TempDir()
+	sessionStore := session.NewMemoryStore()
+	accountStore := account.NewFileStore(filepath.Join(dir, "account"))
+	ts := &testSession{
+		chat:     &fakeChatClient{},
+		auth:     fakeAuthClient{passwords: make(map[string]string)},
+		out:      &bytes.Buffer{},
+		logs:     logs,
+		sessions: sessionStore,
+		accounts: accountStore,
+	}
+	for _, username := range []string{"alice", "bob"} {
+		if err := sessionStore.Save(&model.Session{Username: username}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := accountStore.SetActive("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	credentialsStore := credentials.NewFileStore(filepath.Join(dir, "credentials"))
+	searchIndex := search.NewFileIndex(filepath.Join(dir, "index"))
+	outboxStore := outbox.NewFileStore(filepath.Join(dir, "outbox"))
+	subscriptions := subscription.NewManager(ts.chat, testConfig{})
+	t.Cleanup(subscriptions.Close)
+
+	deps := Deps{
+		ChatClient:       ts.chat,
+		AuthClient:       ts.auth,
+		SessionStore:     sessionStore,
+		AccountStore:     accountStore,
+		CredentialsStore: credentialsStore,
+		TokenManager:     token.NewManager(ts.auth, sessionStore, accountStore, credentialsStore, testConfig{}),
+		ChatRegistry:     registry.NewFileRegistry(filepath.Join(dir, "chats")),
+		HistoryStore:     history.NewFileStore(filepath.Join(dir, "messages"), searchIndex),
+		SearchIndex:      searchIndex,
+		OutboxStore:      outboxStore,
+		OutboxFlusher:    outbox.NewFlusher(outboxStore, ts.chat, nil),
+		Subscriptions:    subscriptions,
+		Console:          console.New(ts.out),
+		StreamConfig:     testConfig{},
+		OutputConfig:     testConfig{},
+		LoginConfig:      testConfig{},
+		RoleConfig:       testConfig{},
+	}
+
+	printer = deps.Console
+	factory := commandTreeFactory(deps)
+	newCommandTree = func() *cobra.Command {
+		ts.last = factory()
+		return ts.last
+	}
+	t.Cleanup(func() { newCommandTree = nil })
+
+	return ts
+}
+
+// run выполняет строку так же, как цикл REPL
+func (ts *testSession) run(t *testing.T, args ...string) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	return executeLine(ctx, args)
+}
+
+// output выполняет строку и возвращает напечатанное ею
+func (ts *testSession) output(t *testing.T, args ...string) string {
+	t.Helper()
+
+	ts.out.Reset()
+	if err := ts.run(t, args...); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+
+	return ts.out.String()
+}
+
+// logged возвращает и забывает сообщения лога с уровнем не ниже level
+func (ts *testSession) logged(level zapcore.Level) []string {
+	var messages []string
+	for _, entry := range ts.logs.TakeAll() {
+		if entry.Level >= level {
+			messages = append(messages, entry.Message)
+		}
+	}
+
+	return messages
+}
+
+// active возвращает активный аккаунт
+func (ts *testSession) active(t *testing.T) string {
+	t.Helper()
+
+	username, err := ts.accounts.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return username
+}
+
+// nonDefault возвращает значение флага, отличное от значения по умолчанию
+func nonDefault(f *pflag.Flag) string {
+	switch f.Value.Type() {
+	case "bool":
+		if f.DefValue == "true" {
+			return "false"
+		}
+		return "true"
+	case "int":
+		return "7"
+	default:
+		return "x"
+	}
+}
+
+// commandPaths пути ко всем исполняемым командам дерева
+func commandPaths(root *cobra.Command) [][]string {
+	var paths [][]string
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, child := range cmd.Commands() {
+			if child.Runnable() {
+				paths = append(paths, strings.Fields(child.CommandPath())[1:])
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return paths
+}
+
+// TestREPLFlagsDoNotLeak разбирает каждую команду дважды за сессию: сначала со всеми
+// флагами, затем без них, и проверяет, что второй вызов видит значения по умолчанию
+func TestREPLFlagsDoNotLeak(t *testing.T) {
+	ts := newTestSession(t)
+
+	configPath, logLevel, stateDir := ConfigPath, LogLevel, StateDir
+
+	paths := commandPaths(newCommandTree())
+	if len(paths) == 0 {
+		t.Fatal("no commands registered")
+	}
+
+	for _, path := range paths {
+		name := strings.Join(path, " ")
+
+		cmd, _, err := newCommandTree().Find(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		args := append([]string(nil), path...)
+		cmd.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+			args = append(args, "--"+f.Name+"="+nonDefault(f))
+		})
+		args = append(args,
+			"--config-path=other.env",
+			"--log-level=debug",
+			"--state-dir="+t.TempDir(),
+			"--as=alice",
+			"--help",
+		)
+
+		if err := ts.run(t, args...); err != nil {
+			t.Fatalf("%s: first run with flags: %v", name, err)
+		}
+
+		if err := ts.run(t, append(append([]string(nil), path...), "--help")...); err != nil {
+			t.Fatalf("%s: second run: %v", name, err)
+		}
+
+		cmd, _, err = ts.last.Find(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if f.Name == "help" {
+				return
+			}
+			if f.Changed || f.Value.String() != f.DefValue {
+				t.Errorf("%s: flag --%s leaked into the next line: %q", name, f.Name, f.Value.String())
+			}
+		})
+	}
+
+	if ConfigPath != configPath || LogLevel != logLevel || StateDir != stateDir {
+		t.Errorf("startup flags changed by REPL lines: %q %q %q", ConfigPath, LogLevel, StateDir)
+	}
+}
+
+func TestREPLSendMessageChatIDDoesNotLeak(t *testing.T) {
+	ts := newTestSession(t)
+
+	if err := ts.run(t, "send-message", "--chat-id=5", "hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ts.run(t, "send-message", "hello")
+	if err == nil || !strings.Contains(err.Error(), `"chat-id" not set`) {
+		t.Fatalf("second send-message error = %v, want missing --chat-id", err)
+	}
+
+	sent := ts.chat.messages()
+	if len(sent) != 1 || sent[0].ChatID != "5" || sent[0].Text != "hi" {
+		t.Fatalf("sent = %+v, want only hi to chat 5", sent)
+	}
+}
+
+func TestREPLAsFlagDoesNotLeak(t *testing.T) {
+	ts := newTestSession(t)
+
+	for _, line := range [][]string{
+		{"send-message", "--as=alice", "--chat-id=5", "first"},
+		{"send-message", "--chat-id=5", "second"},
+		{"send-message", "--as", "alice", "--chat-id=5", "third"},
+		{"send-message", "--chat-id=5", "fourth"},
+	} {
+		if err := ts.run(t, line...); err != nil {
+			t.Fatalf("%v: %v", line, err)
+		}
+	}
+
+	want := []string{"alice", "bob", "alice", "bob"}
+
+	sent := ts.chat.messages()
+	if len(sent) != len(want) {
+		t.Fatalf("sent %d messages, want %d", len(sent), len(want))
+	}
+	for i, msg := range sent {
+		if msg.Username != want[i] {
+			t.Errorf("message %q sent as %q, want %q", msg.Text, msg.Username, want[i])
+		}
+	}
+}
+
+func TestREPLAcceptsStartupFlags(t *testing.T) {
+	ts := newTestSession(t)
+
+	err := ts.run(t, "chats", "--log-level=debug", "--config-path=other.env", "--state-dir", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ts.out.String(), "No known chats yet.") {
+		t.Fatalf("unexpected output:\n%s", ts.out.String())
+	}
+}
+
+// TestREPLStatefulCommandsRunTwice выполняет команды, меняющие состояние, дважды
+// за одну сессию и проверяет, что второй запуск видит результат первого, а не
+// остатки его флагов
+func TestREPLStatefulCommandsRunTwice(t *testing.T) {
+	ts := newTestSession(t)
+	ts.auth.passwords["carol"] = "pw"
+
+	for i := 0; i < 2; i++ {
+		ts.output(t, "login", "--username=carol", "--password=pw")
+		if got := ts.logged(zapcore.InfoLevel); !reflect.DeepEqual(got, []string{"Logged in successfully"}) {
+			t.Fatalf("login #%d logged %q", i+1, got)
+		}
+		if active := ts.active(t); active != "carol" {
+			t.Fatalf("login #%d: active account = %q, want carol", i+1, active)
+		}
+	}
+	if s, err := ts.sessions.Load("carol"); err != nil || s.RefreshToken == "" {
+		t.Fatalf("carol session = %+v, %v", s, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		out := ts.output(t, "accounts", "list")
+		for _, username := range []string{"alice", "bob", "carol"} {
+			if !strings.Contains(out, username) {
+				t.Fatalf("accounts list #%d does not show %s:\n%s", i+1, username, out)
+			}
+		}
+		if !regexp.MustCompile(`(?m)^\*\s+carol\s+valid`).MatchString(out) {
+			t.Fatalf("accounts list #%d does not mark carol as active:\n%s", i+1, out)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		ts.output(t, "accounts", "switch", "bob")
+		if got := ts.logged(zapcore.InfoLevel); !reflect.DeepEqual(got, []string{"Switched account"}) {
+			t.Fatalf("accounts switch #%d logged %q", i+1, got)
+		}
+		if active := ts.active(t); active != "bob" {
+			t.Fatalf("accounts switch #%d: active account = %q, want bob", i+1, active)
+		}
+	}
+
+	if out := ts.output(t, "chats"); !strings.Contains(out, "No known chats yet.") {
+		t.Fatalf("chats before sending:\n%s", out)
+	}
+	ts.output(t, "send-message", "--chat-id=5", "deploy", "done")
+	if got := ts.logged(zapcore.WarnLevel); len(got) != 0 {
+		t.Fatalf("send-message logged %q", got)
+	}
+
+	var first string
+	for i := 0; i < 2; i++ {
+		out := ts.output(t, "chats")
+		if !regexp.MustCompile(`(?m)^5\s+-\s+bob\s`).MatchString(out) {
+			t.Fatalf("chats #%d does not list chat 5:\n%s", i+1, out)
+		}
+		if i == 0 {
+			first = out
+		} else if out != first {
+			t.Fatalf("chats changed between runs:\n%s\n%s", first, out)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		out := ts.output(t, "history", "--chat-id=5")
+		if strings.Count(out, "[bob]: deploy done") != 1 {
+			t.Fatalf("history #%d:\n%s", i+1, out)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		out := ts.output(t, "search", "deploy")
+		// Совпадения подсвечиваются, поэтому проверяем строку по частям
+		if strings.Count(out, "#5 [bob]:") != 1 || !strings.Contains(out, "deploy") || !strings.Contains(out, "done") {
+			t.Fatalf("search #%d:\n%s", i+1, out)
+		}
+		if got := ts.logged(zapcore.WarnLevel); len(got) != 0 {
+			t.Fatalf("search #%d logged %q", i+1, got)
+		}
+	}
+
+	ts.chat.failSends(status.Error(codes.Unavailable, "server is down"))
+	ts.output(t, "send-message", "--chat-id=5", "queued", "text")
+	ts.logs.TakeAll()
+
+	for i := 0; i < 2; i++ {
+		out := ts.output(t, "outbox", "list")
+		if strings.Count(out, "queued text") != 1 || !strings.Contains(out, "pending") {
+			t.Fatalf("outbox list #%d:\n%s", i+1, out)
+		}
+	}
+
+	ts.output(t, "outbox", "drop", "--all")
+	if got := ts.logged(zapcore.InfoLevel); !reflect.DeepEqual(got, []string{"Messages dropped from outbox"}) {
+		t.Fatalf("outbox drop logged %q", got)
+	}
+	if out := ts.output(t, "outbox", "drop", "--all"); !strings.Contains(out, "Outbox is empty.") {
+		t.Fatalf("second outbox drop:\n%s", out)
+	}
+	if out := ts.output(t, "outbox", "list"); !strings.Contains(out, "Outbox is empty.") {
+		t.Fatalf("outbox list after drop:\n%s", out)
+	}
+
+	ts.output(t, "logout", "carol")
+	if got := ts.logged(zapcore.InfoLevel); !reflect.DeepEqual(got, []string{"Logged out"}) {
+		t.Fatalf("logout logged %q", got)
+	}
+	ts.output(t, "logout", "carol")
+	if got := ts.logged(zapcore.InfoLevel); !reflect.DeepEqual(got, []string{"no saved session for account"}) {
+		t.Fatalf("second logout logged %q", got)
+	}
+	if active := ts.active(t); active != "bob" {
+		t.Fatalf("logout of another account changed the active one to %q", active)
+	}
+	if out := ts.output(t, "accounts", "list"); strings.Contains(out, "carol") {
+		t.Fatalf("carol is still listed after logout:\n%s", out)
+	}
+}
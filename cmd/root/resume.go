@@ -0,0 +1,124 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/clients"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/chat-cli/internal/token"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const loginHint = "Please login: login --username=username"
+
+// resumeSession восстанавливает сессию последнего активного аккаунта,
+// чтобы не вводить пароль при каждом запуске
+func resumeSession(
+	ctx context.Context,
+	authClient clients.AuthServiceClient,
+	sessionStore session.Store,
+	accountStore account.Store,
+	tokenManager token.Manager,
+	loginConfig config.LoginConfig,
+) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	username, err := accountStore.Active()
+	if err != nil {
+		if !errors.Is(err, account.ErrNoActiveAccount) {
+			logger.Error("failed to read active account", zap.Error(err))
+		}
+		importSession(ctx, accountStore, tokenManager, loginConfig)
+		return
+	}
+
+	s, err := sessionStore.Load(username)
+	if err != nil {
+		logger.Warn("failed to load previous session", zap.String("username", username), zap.Error(err))
+		reloginSession(ctx, accountStore, tokenManager, username, "could not be loaded")
+		return
+	}
+
+	claims, err := token.ParseClaims(s.RefreshToken)
+	if err != nil || claims.Expired(time.Now()) {
+		reloginSession(ctx, accountStore, tokenManager, username, "has expired")
+		return
+	}
+
+	accessToken, err := authClient.GetAccessToken(ctx, s.RefreshToken)
+	switch status.Code(err) {
+	case codes.OK:
+		if err := saveAccessToken(sessionStore, s, accessToken); err != nil {
+			logger.Error("failed to save session", zap.Error(err))
+		}
+	case codes.Unavailable, codes.DeadlineExceeded:
+		// Сервер недоступен: сессию оставляем, менеджер токенов повторит попытку
+		logger.Warn("auth service is unavailable, tokens will be refreshed later", zap.Error(err))
+	default:
+		reloginSession(ctx, accountStore, tokenManager, username, "was rejected by the server")
+		return
+	}
+
+	tokenManager.Start(username)
+
+	printer.Printf("Welcome back, %s! Resumed your previous session.", username)
+}
+
+// reloginSession пробует войти заново по сохранённым учётным данным
+func reloginSession(ctx context.Context, accountStore account.Store, tokenManager token.Manager, username string, reason string) {
+	if err := tokenManager.Relogin(ctx, username); err != nil {
+		printer.Printf("Session for %s %s.\n%s", username, reason, loginHint)
+		return
+	}
+
+	if err := activateAccount(accountStore, tokenManager, username); err != nil {
+		logger.Error("failed to set active account", zap.Error(err))
+		return
+	}
+
+	printer.Printf("Welcome back, %s! Logged in with saved credentials.", username)
+}
+
+// importSession создаёт сессию из CHAT_CLI_REFRESH_TOKEN, если он задан
+func importSession(ctx context.Context, accountStore account.Store, tokenManager token.Manager, loginConfig config.LoginConfig) {
+	if loginConfig.RefreshToken() == "" {
+		printer.Printf(loginHint)
+		return
+	}
+
+	username, err := tokenManager.Import(ctx, loginConfig.RefreshToken())
+	if err != nil {
+		logger.Error("failed to login with CHAT_CLI_REFRESH_TOKEN", zap.Error(err))
+		printer.Printf(loginHint)
+		return
+	}
+
+	if err := activateAccount(accountStore, tokenManager, username); err != nil {
+		logger.Error("failed to set active account", zap.Error(err))
+		return
+	}
+
+	printer.Printf("Welcome, %s! Logged in with CHAT_CLI_REFRESH_TOKEN.", username)
+}
+
+// saveAccessToken сохраняет новый access token, не затирая refresh token,
+// который мог обновить другой процесс
+func saveAccessToken(sessionStore session.Store, s *Session, accessToken string) error {
+	s.AccessToken = accessToken
+	s.RefreshedAt = time.Now()
+
+	return sessionStore.Update(s.Username, func(stored *Session) error {
+		stored.AccessToken = s.AccessToken
+		stored.RefreshedAt = s.RefreshedAt
+
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package root
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/chat-cli/internal/token"
+	"github.com/spf13/cobra"
+)
+
+const (
+	roleAnnotation = "required-role"
+	roleAdmin      = "admin"
+)
+
+// requireRole помечает команду как доступную только указанной роли.
+// Остальным ролям команда не показывается в help
+func requireRole(cmd *cobra.Command, role string) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[roleAnnotation] = role
+
+	return cmd
+}
+
+type roleResolver struct {
+	sessionStore session.Store
+	accountStore account.Store
+	roleConfig   config.RoleConfig
+}
+
+// currentRole возвращает имя роли текущего аккаунта или пустую строку,
+// если вход не выполнен
+func (r *roleResolver) currentRole(ctx context.Context) string {
+	username, err := account.Current(ctx, r.accountStore)
+	if err != nil {
+		return ""
+	}
+
+	s, err := r.sessionStore.Load(username)
+	if err != nil {
+		return ""
+	}
+
+	for _, t := range []string{s.AccessToken, s.RefreshToken} {
+		if claims, err := token.ParseClaims(t); err == nil && claims.Role != "" {
+			return r.roleConfig.RoleName(claims.Role)
+		}
+	}
+
+	return ""
+}
+
+// checkRole отклоняет команду локально, не дожидаясь ошибки от сервера
+func (r *roleResolver) checkRole(ctx context.Context, cmd *cobra.Command) error {
+	required, ok := cmd.Annotations[roleAnnotation]
+	if !ok {
+		return nil
+	}
+
+	role := r.currentRole(ctx)
+	if role == required {
+		return nil
+	}
+	if role == "" {
+		return fmt.Errorf("%s requires %s role, please login first", cmd.Name(), required)
+	}
+
+	return fmt.Errorf("%s requires %s role, your role is %s", cmd.Name(), required, role)
+}
+
+// updateVisibility скрывает из help и usage команды, недоступные текущей роли
+func (r *roleResolver) updateVisibility(ctx context.Context, root *cobra.Command) {
+	role := r.currentRole(ctx)
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, child := range cmd.Commands() {
+			if required, ok := child.Annotations[roleAnnotation]; ok {
+				child.Hidden = required != role
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+}
@@ -0,0 +1,67 @@
+package root
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+// loginAs сохраняет сессию с токенами указанной роли
+func (ts *testSession) loginAs(t *testing.T, username, role string) {
+	t.Helper()
+
+	err := ts.sessions.Save(&model.Session{
+		Username:     username,
+		AccessToken:  fakeToken(username, role),
+		RefreshToken: fakeToken(username, role),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listsCommand сообщает, есть ли команда в списке команд help
+func listsCommand(help, name string) bool {
+	for _, line := range strings.Split(help, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestHelpHidesCommandsOfOtherRoles(t *testing.T) {
+	ts := newTestSession(t)
+	ts.loginAs(t, "alice", roleAdmin)
+	ts.loginAs(t, "bob", "user")
+
+	for _, args := range [][]string{{"--help"}, {"help"}} {
+		ts.out.Reset()
+		if err := ts.run(t, args...); err != nil {
+			t.Fatal(err)
+		}
+		if listsCommand(ts.out.String(), "delete-chat") {
+			t.Errorf("%v lists delete-chat for a user:\n%s", args, ts.out.String())
+		}
+		if !listsCommand(ts.out.String(), "send-message") {
+			t.Errorf("%v does not list send-message:\n%s", args, ts.out.String())
+		}
+	}
+
+	if err := ts.accounts.SetActive("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	ts.out.Reset()
+	if err := ts.run(t, "--help"); err != nil {
+		t.Fatal(err)
+	}
+	if !listsCommand(ts.out.String(), "delete-chat") {
+		t.Errorf("--help does not list delete-chat for an admin:\n%s", ts.out.String())
+	}
+	if strings.Contains(ts.out.String(), "requires") {
+		t.Errorf("--help shows a role hint for a visible command:\n%s", ts.out.String())
+	}
+}
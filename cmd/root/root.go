@@ -2,39 +2,74 @@ package root
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/Mobo140/chat-cli/internal/account"
 	"github.com/Mobo140/chat-cli/internal/clients"
 	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"github.com/Mobo140/chat-cli/internal/console"
+	"github.com/Mobo140/chat-cli/internal/credentials"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/outbox"
+	"github.com/Mobo140/chat-cli/internal/registry"
+	"github.com/Mobo140/chat-cli/internal/search"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/chat-cli/internal/shellwords"
+	"github.com/Mobo140/chat-cli/internal/stream"
+	"github.com/Mobo140/chat-cli/internal/subscription"
+	"github.com/Mobo140/chat-cli/internal/token"
 	"github.com/Mobo140/platform_common/pkg/logger"
 	"github.com/chzyer/readline"
-	"github.com/gofrs/flock"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/metadata"
 )
 
 const (
-	refreshTokenCronInterval = 23 * time.Hour
-	accessTokenCronInterval  = 14 * time.Minute
-	timeout                  = 20 * time.Second
+	timeout = 20 * time.Second
+
+	replPrompt = "> "
+	// continuationPrompt показывается, пока открыты кавычки или блок <<EOF
+	continuationPrompt = "... "
+
+	asFlagUsage = "Run a single command as another logged in account"
 )
 
 var (
-	ConfigPath string
-	LogLevel   string
+	ConfigPath  string
+	LogLevel    string
+	StateDir    string
+	HistoryFile string
 )
 
+// beforeExit вызывается при выходе из REPL
+var beforeExit func()
+
+// newCommandTree создаёт дерево команд для одной строки REPL, чтобы значения
+// флагов не переходили из одного вызова в следующий
+var newCommandTree func() *cobra.Command
+
+// printer вывод, безопасный для фоновых горутин во время ввода в REPL
+var printer *console.Console
+
 func init() {
-	RootCmd.PersistentFlags().StringVar(&ConfigPath, "config-path", ".env", "Path to config file")
-	RootCmd.PersistentFlags().StringVarP(&LogLevel, "log-level", "l", "info", "Log level")
+	addRootFlags(RootCmd, &ConfigPath, &LogLevel, &StateDir)
+}
+
+// startupFlags флаги, которые читаются один раз при запуске приложения
+var startupFlags = []string{"config-path", "log-level", "state-dir"}
+
+// addRootFlags регистрирует общие флаги корня команд
+func addRootFlags(cmd *cobra.Command, configPath, logLevel, stateDir *string) {
+	cmd.PersistentFlags().StringVar(configPath, "config-path", ".env", "Path to config file")
+	cmd.PersistentFlags().StringVarP(logLevel, "log-level", "l", "info", "Log level")
+	cmd.PersistentFlags().String("as", "", asFlagUsage)
+	cmd.PersistentFlags().StringVar(stateDir, "state-dir", "", "Directory for sessions, logs and history (default: XDG directories)")
 }
 
 var RootCmd = &cobra.Command{
@@ -46,364 +81,474 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// Interactive сообщает, что аргументы командной строки запускают REPL, а не команду
+func Interactive(args []string) bool {
+	cmd, _, err := RootCmd.Find(args)
+
+	return err == nil && cmd == RootCmd
+}
+
 func StartREPL(cmd *cobra.Command) {
-	rl, err := readline.New("> ")
+	if HistoryFile != "" {
+		if err := scrubHistoryFile(HistoryFile); err != nil {
+			logger.Warn("failed to scrub history file", zap.Error(err))
+		}
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:      replPrompt,
+		HistoryFile: HistoryFile,
+		// История сохраняется вручную, чтобы пароли не попадали в файл
+		DisableAutoSaveHistory: true,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer rl.Close()
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	repl = rl
+	defer func() { repl = nil }()
+
+	detach := printer.Attach(rl)
+	defer detach()
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-	fmt.Println("Welcome to Chat CLI. Type 'exit' to quit or press Ctrl+C.")
+	sig := newInterrupts(ctx, rl)
+	stopSignals := sig.watch()
+	defer stopSignals()
 
-	go func() {
-		<-done
-		fmt.Println("\nReceived interrupt signal. Exiting...")
-		os.Exit(0)
-	}()
+	printer.Printf("Welcome to Chat CLI. Type 'exit' to quit or press Ctrl+D.")
 
-	for {
-		fmt.Println()
+	// pending строки ввода, который ещё не закончен
+	var pending []string
+
+	for !sig.done() {
+		if len(pending) == 0 {
+			printer.Printf("")
+		}
 
 		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			// Ctrl+C отменяет незаконченный ввод
+			if len(pending) > 0 {
+				pending = nil
+				rl.SetPrompt(replPrompt)
+				continue
+			}
+
+			// Ctrl+C сбрасывает набранную строку, на пустой строке дважды подряд завершает REPL
+			if line == "" && sig.interrupt() {
+				break
+			}
+			continue
+		}
 		if err != nil {
 			break
 		}
 
-		line = strings.TrimSpace(line)
-		if line == "" {
+		if len(pending) == 0 && strings.TrimSpace(line) == "" {
 			continue
 		}
+		pending = append(pending, line)
 
-		if line == "exit" || line == "quit" || line == "q" {
-			fmt.Println("Goodbye!")
-			break
+		args, err := shellwords.Split(strings.Join(pending, "\n"))
+		if errors.Is(err, shellwords.ErrIncomplete) {
+			rl.SetPrompt(continuationPrompt)
+			continue
 		}
 
-		if line == "clear" {
-			fmt.Print("\033[H\033[2J") // Очистка экрана
-			continue
+		input := pending
+		pending = nil
+		rl.SetPrompt(replPrompt)
+
+		for _, entry := range historyEntries(input) {
+			if err := rl.SaveHistory(entry); err != nil {
+				logger.Debug("failed to save history", zap.Error(err))
+			}
 		}
 
-		args := strings.Fields(line)
+		if err != nil {
+			printer.Printf("Error: %v", err)
+			continue
+		}
 		if len(args) == 0 {
 			continue
 		}
 
-		cmd.SetArgs(args)
-		if err := cmd.Execute(); err != nil {
-			fmt.Printf("Error: %v\n", err)
+		if len(args) == 1 && (args[0] == "exit" || args[0] == "quit" || args[0] == "q") {
+			printer.Printf("Goodbye!")
+			break
+		}
+
+		if len(args) == 1 && args[0] == "clear" {
+			printer.Write([]byte("\033[H\033[2J")) // Очистка экрана
+			continue
 		}
-		cmd.SetArgs(nil)
 
-		time.Sleep(100 * time.Millisecond)
+		// Ctrl+C во время команды отменяет только её контекст
+		cmdCtx, finish := sig.command()
+
+		if err := executeLine(cmdCtx, args); err != nil {
+			printer.Printf("Error: %v", err)
+		}
+
+		finish()
 	}
-}
 
-func InitCommands(chatClient clients.ChatServiceClient,
-	authClient clients.AuthServiceClient,
-	sessionFile string,
-	loginDoneCh chan struct{},
-) {
-	loginCmd := newLoginCmd(authClient, sessionFile, loginDoneCh)
-	createChatCmd := newCreateChatCmd(chatClient)
-	deleteChatCmd := newDeleteChatCmd(chatClient)
-	sendMessageCmd := newSendMessageCmd(chatClient, sessionFile)
-	connectChatCmd := newConnectChatCmd(chatClient)
-
-	RootCmd.AddCommand(loginCmd)
-	RootCmd.AddCommand(createChatCmd)
-	RootCmd.AddCommand(deleteChatCmd)
-	RootCmd.AddCommand(sendMessageCmd)
-	RootCmd.AddCommand(connectChatCmd)
+	if beforeExit != nil {
+		beforeExit()
+	}
 }
 
-func newCreateChatCmd(chatClient clients.ChatServiceClient) *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "create-chat",
-		Short: "Create a new chat",
-		Run: func(cmd *cobra.Command, args []string) {
-			usernames, _ := cmd.Flags().GetStringArray("username")
+// executeLine выполняет одну строку REPL на новом дереве команд
+func executeLine(ctx context.Context, args []string) error {
+	tree := newCommandTree()
+	tree.SetArgs(args)
 
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			defer cancel()
+	return tree.ExecuteContext(ctx)
+}
 
-			logger.Info("Creating chat", zap.Any("usernames", usernames))
+// Deps зависимости, необходимые командам
+type Deps struct {
+	ChatClient       clients.ChatServiceClient
+	AuthClient       clients.AuthServiceClient
+	SessionStore     session.Store
+	AccountStore     account.Store
+	CredentialsStore credentials.Store
+	TokenManager     token.Manager
+	ChatRegistry     registry.Registry
+	HistoryStore     history.Store
+	SearchIndex      search.Index
+	OutboxStore      outbox.Store
+	OutboxFlusher    *outbox.Flusher
+	Subscriptions    *subscription.Manager
+	Console          *console.Console
+	StreamConfig     config.StreamConfig
+	OutputConfig     config.OutputConfig
+	LoginConfig      config.LoginConfig
+	RoleConfig       config.RoleConfig
+}
 
-			chatID, err := chatClient.Create(ctx, usernames)
-			if err != nil {
-				logger.Error("failed to create chat", zap.Error(err))
-				return
-			}
+// InitCommands подключает команды к RootCmd для запуска из командной строки
+// и готовит фабрику деревьев команд для строк REPL
+func InitCommands(deps Deps) {
+	printer = deps.Console
 
-			logger.Info("Chat created successfully", zap.String("chat_id", chatID))
-		},
+	addCommands(RootCmd, deps)
+
+	RootCmd.PreRun = func(cmd *cobra.Command, args []string) {
+		resumeSession(cmd.Context(), deps.AuthClient, deps.SessionStore, deps.AccountStore, deps.TokenManager, deps.LoginConfig)
 	}
 
-	cmd.Flags().StringArray("username", []string{}, "Usernames to add to chat (can be specified multiple times)")
-	cmd.MarkFlagRequired("username")
+	newCommandTree = commandTreeFactory(deps)
 
-	return cmd
+	beforeExit = func() {
+		warnPendingOutbox(deps.OutboxStore)
+	}
 }
 
-func getSessionFilePath(basePath, username string) string {
-	return fmt.Sprintf("%s.%s", basePath, username)
-}
+// commandTreeFactory возвращает фабрику деревьев команд для строк REPL
+func commandTreeFactory(deps Deps) func() *cobra.Command {
+	return func() *cobra.Command {
+		cmd := &cobra.Command{
+			Use:   RootCmd.Use,
+			Short: RootCmd.Short,
+			Long:  RootCmd.Long,
+			// Ошибку печатает цикл REPL
+			SilenceErrors: true,
+		}
+		// Настройки уже загружены, поэтому в REPL флаги запуска принимаются,
+		// но не меняют глобальные значения
+		var configPath, logLevel, stateDir string
+		addRootFlags(cmd, &configPath, &logLevel, &stateDir)
+
+		addCommands(cmd, deps)
+
+		preRun := cmd.PersistentPreRunE
+		cmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+			for _, name := range startupFlags {
+				if c.Flags().Changed(name) {
+					logger.Warn("flag only applies when chat-cli starts, ignoring it", zap.String("flag", "--"+name))
+				}
+			}
 
-func loadSession(sessionFile string) (*Session, error) {
-	data, err := os.ReadFile(sessionFile)
-	if err != nil {
-		return nil, err
-	}
-	var s Session
-	if err := json.Unmarshal(data, &s); err != nil {
-		return nil, err
+			return preRun(c, args)
+		}
+
+		return cmd
 	}
-	return &s, nil
 }
 
-func safeWriteSessionFile(session *Session, sessionFile string) error {
-	lock := flock.New(sessionFile + ".lock")
-	defer lock.Unlock()
+// addCommands добавляет в корень все команды и общие проверки перед их запуском
+func addCommands(root *cobra.Command, deps Deps) {
+	root.SetOut(printer)
+	root.SetErr(printer)
 
-	ok, err := lock.TryLock()
-	if err != nil || !ok {
-		return err
+	roles := &roleResolver{
+		sessionStore: deps.SessionStore,
+		accountStore: deps.AccountStore,
+		roleConfig:   deps.RoleConfig,
 	}
 
-	data, err := json.MarshalIndent(session, "", "  ")
-	if err != nil {
-		return err
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if asUser, _ := cmd.Flags().GetString("as"); asUser != "" {
+			if _, err := deps.SessionStore.Load(asUser); err != nil {
+				return fmt.Errorf("account %q is not logged in: %w", asUser, err)
+			}
+			ctx = account.WithUsername(ctx, asUser)
+		}
+
+		cmd.SetContext(ctx)
+		roles.updateVisibility(ctx, root)
+
+		return roles.checkRole(ctx, cmd)
 	}
-	return os.WriteFile(sessionFile, data, 0644)
+
+	loginCmd := newLoginCmd(deps.AccountStore, deps.CredentialsStore, deps.TokenManager, deps.LoginConfig)
+	logoutCmd := newLogoutCmd(deps.SessionStore, deps.AccountStore, deps.TokenManager, deps.Subscriptions)
+	accountsCmd := newAccountsCmd(deps.SessionStore, deps.AccountStore, deps.TokenManager)
+	whoamiCmd := newWhoamiCmd(deps.AuthClient, deps.SessionStore, deps.AccountStore)
+	createChatCmd := newCreateChatCmd(deps.ChatClient, deps.AccountStore, deps.ChatRegistry)
+	deleteChatCmd := requireRole(newDeleteChatCmd(deps.ChatClient, deps.AccountStore, deps.ChatRegistry), roleAdmin)
+	sendMessageCmd := newSendMessageCmd(
+		deps.ChatClient,
+		deps.SessionStore,
+		deps.AccountStore,
+		deps.ChatRegistry,
+		deps.HistoryStore,
+		deps.OutboxStore,
+		deps.OutboxFlusher,
+	)
+	connectChatCmd := newConnectChatCmd(
+		deps.ChatClient,
+		deps.AccountStore,
+		deps.ChatRegistry,
+		deps.HistoryStore,
+		deps.StreamConfig,
+		deps.OutputConfig,
+	)
+	joinCmd := newJoinCmd(deps)
+	historyCmd := newHistoryCmd(deps.AccountStore, deps.ChatRegistry, deps.HistoryStore)
+	searchCmd := newSearchCmd(deps.AccountStore, deps.ChatRegistry, deps.HistoryStore, deps.SearchIndex)
+	exportCmd := newExportCmd(deps.AccountStore, deps.ChatRegistry, deps.HistoryStore)
+	outboxCmd := newOutboxCmd(deps.AccountStore, deps.OutboxStore, deps.OutboxFlusher)
+	chatsCmd := newChatsCmd(deps.AccountStore, deps.ChatRegistry)
+	chatCmd := newChatCmd(deps.AccountStore, deps.ChatRegistry)
+	subscribeCmd := newSubscribeCmd(deps)
+	unsubscribeCmd := newUnsubscribeCmd(deps)
+	muteCmd := newMuteCmd(deps, true)
+	unmuteCmd := newMuteCmd(deps, false)
+	subscriptionsCmd := newSubscriptionsCmd(deps)
+
+	root.AddCommand(loginCmd)
+	root.AddCommand(logoutCmd)
+	root.AddCommand(accountsCmd)
+	root.AddCommand(whoamiCmd)
+	root.AddCommand(createChatCmd)
+	root.AddCommand(deleteChatCmd)
+	root.AddCommand(sendMessageCmd)
+	root.AddCommand(connectChatCmd)
+	root.AddCommand(joinCmd)
+	root.AddCommand(historyCmd)
+	root.AddCommand(searchCmd)
+	root.AddCommand(exportCmd)
+	root.AddCommand(outboxCmd)
+	root.AddCommand(chatsCmd)
+	root.AddCommand(chatCmd)
+	root.AddCommand(subscribeCmd)
+	root.AddCommand(unsubscribeCmd)
+	root.AddCommand(muteCmd)
+	root.AddCommand(unmuteCmd)
+	root.AddCommand(subscriptionsCmd)
+
+	// --help обрабатывается до PersistentPreRunE, поэтому команды скрываются
+	// по роли активного аккаунта сразу. С --as видимость уточняется перед запуском
+	roles.updateVisibility(context.Background(), root)
 }
 
-func newLoginCmd(authClient clients.AuthServiceClient, sessionFile string, loginDoneCh chan struct{}) *cobra.Command {
+func newCreateChatCmd(
+	chatClient clients.ChatServiceClient,
+	accountStore account.Store,
+	chatRegistry registry.Registry,
+) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "login",
-		Short: "Login to the chat",
+		Use:   "create-chat",
+		Short: "Create a new chat",
 		Run: func(cmd *cobra.Command, args []string) {
-			username, _ := cmd.Flags().GetString("username")
-			password, _ := cmd.Flags().GetString("password")
+			usernames, _ := cmd.Flags().GetStringArray("username")
 
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
 			defer cancel()
 
-			refreshToken, err := authClient.Login(ctx, username, password)
-			if err != nil {
-				logger.Error("failed to login", zap.Error(err))
-				return
-			}
+			logger.Info("Creating chat", zap.Any("usernames", usernames))
 
-			accessToken, err := authClient.GetAccessToken(ctx, refreshToken)
+			chatID, err := chatClient.Create(ctx, usernames)
 			if err != nil {
-				logger.Error("failed to get access token", zap.Error(err))
-				return
-			}
-
-			session := &Session{
-				Username:     username,
-				AccessToken:  accessToken,
-				RefreshToken: refreshToken,
-			}
-
-			userSessionFile := getSessionFilePath(sessionFile, username)
-			if err := safeWriteSessionFile(session, userSessionFile); err != nil {
-				logger.Error("failed to save session", zap.Error(err))
-				return
-			}
-
-			// Устанавливаем переменную окружения для текущего пользователя
-			if err := os.Setenv("CHAT_USERNAME", username); err != nil {
-				logger.Error("failed to set CHAT_USERNAME environment variable", zap.Error(err))
+				logger.Error("failed to create chat", zap.Error(err))
 				return
 			}
 
-			logger.Info("Logged in successfully", zap.String("username", username))
+			touchChat(cmd.Context(), accountStore, chatRegistry, model.Chat{
+				ID:      chatID,
+				Members: usernames,
+			})
 
-			select {
-			case loginDoneCh <- struct{}{}:
-				logger.Debug("Sent signal about new login")
-			default:
-				select {
-				case <-loginDoneCh:
-				default:
-				}
-				loginDoneCh <- struct{}{}
-			}
+			logger.Info("Chat created successfully", zap.String("chat_id", chatID))
 		},
 	}
 
-	cmd.Flags().String("username", "", "Username for login")
-	cmd.Flags().String("password", "", "Password for login")
+	cmd.Flags().StringArray("username", []string{}, "Usernames to add to chat (can be specified multiple times)")
 	cmd.MarkFlagRequired("username")
-	cmd.MarkFlagRequired("password")
 
 	return cmd
 }
 
-func RefreshTokenCron(authClient clients.AuthServiceClient, sessionFile string, loginDoneCh chan struct{}, refreshTokenDoneCh chan struct{}) {
-	for {
-		<-loginDoneCh
-
-		select {
-		case <-refreshTokenDoneCh:
-		default:
-		}
-
-		username := os.Getenv("CHAT_USERNAME")
-		if username == "" {
-			logger.Error("CHAT_USERNAME environment variable is not set")
-			continue
-		}
+func newConnectChatCmd(
+	chatClient clients.ChatServiceClient,
+	accountStore account.Store,
+	chatRegistry registry.Registry,
+	historyStore history.Store,
+	streamConfig config.StreamConfig,
+	outputConfig config.OutputConfig,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "connect-chat",
+		Short: "Connect to chat",
+		Long: `Connect to one or more chats and start receiving messages.
+Repeat --chat-id to follow several chats at once, each line is prefixed with the chat name.
+Use Ctrl+C to disconnect from chat.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			chatRefs, _ := cmd.Flags().GetStringSlice("chat-id")
+			username, _ := cmd.Flags().GetString("username")
 
-		userSessionFile := getSessionFilePath(sessionFile, username)
-		for {
-			session, err := loadSession(userSessionFile)
+			acc, err := account.Current(cmd.Context(), accountStore)
 			if err != nil {
-				logger.Error("failed to load session", zap.Error(err))
-				break
+				logger.Error("failed to get current account", zap.Error(err))
+				return
 			}
 
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			newRefreshToken, err := authClient.GetRefreshToken(ctx, session.RefreshToken)
-			cancel()
-
+			renderer, err := outputRenderer(cmd, outputConfig)
 			if err != nil {
-				logger.Error("failed to refresh token", zap.Error(err))
-				break
-			}
-
-			session.RefreshToken = newRefreshToken
-
-			if err := safeWriteSessionFile(session, userSessionFile); err != nil {
-				logger.Error("failed to save session", zap.Error(err))
-				break
-			}
-
-			logger.Info("Refresh token updated")
-
-			select {
-			case refreshTokenDoneCh <- struct{}{}:
-				logger.Debug("Sent signal to start access token updates")
-			default:
+				logger.Error("invalid output format", zap.Error(err))
+				return
 			}
 
-			time.Sleep(refreshTokenCronInterval)
-		}
-	}
-}
+			labels := chatLabels(acc, chatRegistry)
+			output := newChatOutput(historyStore, renderer, printer, true)
 
-func AccessTokenCron(authClient clients.AuthServiceClient, sessionFile string, refreshTokenDoneCh chan struct{}) {
-	<-refreshTokenDoneCh
+			// Потоки живут только до выхода из команды, поэтому менеджер свой
+			subscriptions := subscription.NewManager(chatClient, streamConfig)
+			defer subscriptions.Close()
 
-	username := os.Getenv("CHAT_USERNAME")
-	if username == "" {
-		logger.Error("CHAT_USERNAME environment variable is not set")
-		return
-	}
-
-	userSessionFile := getSessionFilePath(sessionFile, username)
-	for {
-		session, err := loadSession(userSessionFile)
-		if err != nil {
-			logger.Error("failed to load session", zap.Error(err))
-			continue
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		newAccessToken, err := authClient.GetAccessToken(ctx, session.RefreshToken)
-		cancel()
-
-		if err != nil {
-			logger.Error("failed to generate access token", zap.Error(err))
-			continue
-		}
-
-		session.AccessToken = newAccessToken
-		if err := safeWriteSessionFile(session, userSessionFile); err != nil {
-			logger.Error("failed to save session", zap.Error(err))
-			continue
-		}
-
-		logger.Info("Access token updated")
-		time.Sleep(accessTokenCronInterval)
-	}
-}
-
-func newConnectChatCmd(chatClient clients.ChatServiceClient) *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "connect-chat",
-		Short: "Connect to chat",
-		Long: `Connect to chat and start receiving messages. 
-Use Ctrl+C to disconnect from chat.`,
-		Run: func(cmd *cobra.Command, args []string) {
-			chatID, _ := cmd.Flags().GetString("chat-id")
-			username, _ := cmd.Flags().GetString("username")
+			for _, chatRef := range chatRefs {
+				chatID, err := resolveChatID(cmd.Context(), accountStore, chatRegistry, chatRef)
+				if err != nil {
+					logger.Error("failed to resolve chat", zap.String("chat", chatRef), zap.Error(err))
+					return
+				}
 
-			logger.Info("Attempting to connect to chat...",
-				zap.String("chat_id", chatID),
-				zap.String("username", username))
+				touchChat(cmd.Context(), accountStore, chatRegistry, model.Chat{
+					ID:      chatID,
+					Members: []string{username},
+				})
 
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
+				label := chatID
+				if alias, ok := labels[chatID]; ok {
+					label = alias
+				}
 
-			done := make(chan os.Signal, 1)
-			signal.Notify(done, os.Interrupt, syscall.SIGTERM)
-			defer signal.Stop(done)
+				logger.Info("Attempting to connect to chat...",
+					zap.String("chat_id", chatID),
+					zap.String("username", username))
 
-			errChan := make(chan error, 1)
+				if err := subscriptions.Subscribe(chatID, label, acc, username, output); err != nil {
+					logger.Error("failed to connect to chat", zap.String("chat_id", chatID), zap.Error(err))
+					return
+				}
+			}
 
+			stopped := make(chan struct{})
 			go func() {
-				errChan <- chatClient.ConnectChat(ctx, chatID, username)
+				subscriptions.Wait()
+				close(stopped)
 			}()
 
-			logger.Info("Successfully connected to chat. Press Ctrl+C to disconnect.",
-				zap.String("chat_id", chatID),
-				zap.String("username", username))
-
 			select {
-			case <-done:
-				logger.Info("Disconnecting from chat",
-					zap.String("chat_id", chatID),
-					zap.String("username", username))
-				return
-			case err := <-errChan:
-				if err != nil {
-					logger.Error("Error in chat connection",
-						zap.Error(err),
-						zap.String("chat_id", chatID),
-						zap.String("username", username))
+			case <-cmd.Context().Done():
+				logger.Info("Disconnecting from chat", zap.Strings("chats", chatRefs), zap.String("username", username))
+			case <-stopped:
+				for _, info := range subscriptions.List() {
+					if info.Err != nil {
+						logger.Error("Error in chat connection",
+							zap.Error(info.Err),
+							zap.String("chat_id", info.ChatID),
+							zap.String("username", username))
+					}
 				}
-				return
 			}
 		},
 	}
 
-	cmd.Flags().String("chat-id", "", "Chat ID to connect to")
+	cmd.Flags().StringSlice("chat-id", nil, "Chat ID or alias to connect to, can be repeated")
 	cmd.Flags().String("username", "", "Username to connect to chat")
+	addFormatFlag(cmd)
 	cmd.MarkFlagRequired("chat-id")
 	cmd.MarkFlagRequired("username")
 
 	return cmd
 }
 
-func newSendMessageCmd(chatClient clients.ChatServiceClient, sessionFile string) *cobra.Command {
+// logStreamEvent сообщает об изменениях состояния потока чата
+func logStreamEvent(chatID, username string, e stream.Event) {
+	switch {
+	case e.State == stream.StateConnected && e.Attempt == 0:
+		logger.Info("Successfully connected to chat. Press Ctrl+C to disconnect.",
+			zap.String("chat_id", chatID),
+			zap.String("username", username))
+	case e.State == stream.StateConnected:
+		logger.Info("Reconnected to chat", zap.String("chat_id", chatID))
+	case e.State == stream.StateReconnecting:
+		logger.Warn(stream.Describe(e), zap.String("chat_id", chatID))
+	}
+}
+
+func newSendMessageCmd(
+	chatClient clients.ChatServiceClient,
+	sessionStore session.Store,
+	accountStore account.Store,
+	chatRegistry registry.Registry,
+	historyStore history.Store,
+	outboxStore outbox.Store,
+	flusher *outbox.Flusher,
+) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "send-message --chat-id=ID MESSAGE",
 		Short: "Send message to chat",
 		Long: `Send message to chat. The message should be the last argument:
 Example: send-message --chat-id=1 Hello, world!`,
 		Run: func(cmd *cobra.Command, args []string) {
-			chatID, _ := cmd.Flags().GetString("chat-id")
+			chatRef, _ := cmd.Flags().GetString("chat-id")
 
 			if len(args) == 0 {
 				logger.Error("no message provided")
 				return
 			}
 
+			chatID, err := resolveChatID(cmd.Context(), accountStore, chatRegistry, chatRef)
+			if err != nil {
+				logger.Error("failed to resolve chat", zap.Error(err))
+				return
+			}
+
 			message := strings.Join(args, " ")
 
 			logger.Debug("Command arguments:",
@@ -411,25 +556,19 @@ Example: send-message --chat-id=1 Hello, world!`,
 				zap.String("chat_id", chatID),
 				zap.String("message", message))
 
-			username := os.Getenv("CHAT_USERNAME")
-			if username == "" {
-				logger.Error("CHAT_USERNAME environment variable is not set")
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
 				return
 			}
 
-			userSessionFile := getSessionFilePath(sessionFile, username)
-			session, err := loadSession(userSessionFile)
+			session, err := sessionStore.Load(username)
 			if err != nil {
 				logger.Error("failed to load session", zap.Error(err))
 				return
 			}
 
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			defer cancel()
-
-			ctx = addAccessTokenToContext(ctx, session.AccessToken)
-
-			err = chatClient.SendMessage(ctx, &chat.Message{
+			queued, err := deliverMessage(cmd.Context(), chatClient, outboxStore, flusher, username, &chat.Message{
 				ChatID:   chatID,
 				Text:     message,
 				Username: session.Username,
@@ -439,6 +578,25 @@ Example: send-message --chat-id=1 Hello, world!`,
 				return
 			}
 
+			if queued != "" {
+				logger.Warn("Chat server is unreachable, message queued and will be sent automatically",
+					zap.String("id", queued),
+					zap.String("chat_id", chatID))
+				return
+			}
+
+			recordMessage(cmd.Context(), accountStore, historyStore, model.Message{
+				ChatID:   chatID,
+				Username: session.Username,
+				Text:     message,
+				Time:     time.Now(),
+			})
+
+			touchChat(cmd.Context(), accountStore, chatRegistry, model.Chat{
+				ID:      chatID,
+				Members: []string{session.Username},
+			})
+
 			logger.Info("Message sent successfully",
 				zap.String("chat_id", chatID),
 				zap.String("message", message),
@@ -446,30 +604,29 @@ Example: send-message --chat-id=1 Hello, world!`,
 		},
 	}
 
-	cmd.Flags().String("chat-id", "", "Chat ID to send message to")
+	cmd.Flags().String("chat-id", "", "Chat ID or alias to send message to")
 	cmd.MarkFlagRequired("chat-id")
 
 	return cmd
 }
 
-func addAccessTokenToContext(ctx context.Context, accessToken string) context.Context {
-	authHeader := fmt.Sprintf("Bearer %s", accessToken)
-	logger.Debug("Adding auth header to context",
-		zap.String("header", authHeader))
-	return metadata.NewOutgoingContext(ctx, metadata.Pairs("authorization", authHeader))
-}
-
-func newDeleteChatCmd(chatClient clients.ChatServiceClient) *cobra.Command {
-	return &cobra.Command{
+func newDeleteChatCmd(
+	chatClient clients.ChatServiceClient,
+	accountStore account.Store,
+	chatRegistry registry.Registry,
+) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "delete-chat",
 		Short: "Delete an existing chat",
 		Run: func(cmd *cobra.Command, args []string) {
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
 			defer cancel()
 
-			chatID, err := cmd.Flags().GetString("chat_id")
+			chatRef, _ := cmd.Flags().GetString("chat-id")
+
+			chatID, err := resolveChatID(ctx, accountStore, chatRegistry, chatRef)
 			if err != nil {
-				logger.Error("failed to get chat_id", zap.Error(err))
+				logger.Error("failed to resolve chat", zap.Error(err))
 				return
 			}
 
@@ -481,9 +638,20 @@ func newDeleteChatCmd(chatClient clients.ChatServiceClient) *cobra.Command {
 				return
 			}
 
+			if username, err := account.Current(ctx, accountStore); err == nil {
+				if err := chatRegistry.Forget(username, chatID); err != nil && !errors.Is(err, registry.ErrNotFound) {
+					logger.Warn("failed to remove chat from registry", zap.Error(err))
+				}
+			}
+
 			logger.Info("Chat deleted successfully", zap.String("chat_id", chatID))
 		},
 	}
+
+	cmd.Flags().String("chat-id", "", "Chat ID or alias to delete")
+	cmd.MarkFlagRequired("chat-id")
+
+	return cmd
 }
 
 func Execute() {
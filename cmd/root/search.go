@@ -0,0 +1,196 @@
+package root
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/registry"
+	"github.com/Mobo140/chat-cli/internal/search"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSearchLimit = 20
+
+	highlightStart = "\033[1;31m"
+	highlightEnd   = "\033[0m"
+)
+
+func newSearchCmd(
+	accountStore account.Store,
+	chatRegistry registry.Registry,
+	historyStore history.Store,
+	searchIndex search.Index,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search local chat history",
+		Long: `Search messages stored locally. All words must occur in a message,
+text in quotes is matched as a phrase. With --regex the query is a
+regular expression. --since and --until accept a duration (2h) or a date.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			chatRef, _ := cmd.Flags().GetString("chat-id")
+			from, _ := cmd.Flags().GetString("from")
+			sinceValue, _ := cmd.Flags().GetString("since")
+			untilValue, _ := cmd.Flags().GetString("until")
+			useRegex, _ := cmd.Flags().GetBool("regex")
+			contextLines, _ := cmd.Flags().GetInt("context")
+			limit, _ := cmd.Flags().GetInt("limit")
+			reindex, _ := cmd.Flags().GetBool("reindex")
+
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			if reindex {
+				if err := searchIndex.Rebuild(username, historyStore); err != nil {
+					logger.Error("failed to rebuild search index", zap.Error(err))
+					return
+				}
+				logger.Info("Search index rebuilt")
+
+				if len(args) == 0 {
+					return
+				}
+			}
+
+			query, err := searchQuery(args, useRegex)
+			if err != nil {
+				logger.Error("invalid regular expression", zap.Error(err))
+				return
+			}
+			query.Limit = limit
+			query.Context = contextLines
+
+			now := time.Now()
+
+			if query.Since, err = parseTimeFlag(sinceValue, now); err != nil {
+				logger.Error("invalid --since", zap.Error(err))
+				return
+			}
+			if query.Until, err = parseTimeFlag(untilValue, now); err != nil {
+				logger.Error("invalid --until", zap.Error(err))
+				return
+			}
+
+			if chatRef != "" {
+				if query.ChatID, err = resolveChatID(cmd.Context(), accountStore, chatRegistry, chatRef); err != nil {
+					logger.Error("failed to resolve chat", zap.Error(err))
+					return
+				}
+			}
+			query.From = from
+
+			results, err := search.Search(searchIndex, historyStore, username, query)
+			if err != nil {
+				logger.Error("search failed", zap.Error(err))
+				return
+			}
+
+			out := cmd.OutOrStdout()
+
+			if len(results) == 0 {
+				fmt.Fprintln(out, "No messages found.")
+				return
+			}
+
+			printSearchResults(out, results, chatLabels(username, chatRegistry))
+		},
+	}
+
+	cmd.Flags().String("chat-id", "", "Only search this chat (ID or alias)")
+	cmd.Flags().String("from", "", "Only messages from this user")
+	cmd.Flags().String("since", "", "Only messages newer than a duration or date")
+	cmd.Flags().String("until", "", "Only messages older than a duration or date")
+	cmd.Flags().Bool("regex", false, "Treat the query as a regular expression")
+	cmd.Flags().IntP("context", "C", 0, "Messages of context to show around each match")
+	cmd.Flags().Int("limit", defaultSearchLimit, "Maximum number of matches")
+	cmd.Flags().Bool("reindex", false, "Rebuild the search index from the stored history")
+
+	return cmd
+}
+
+// searchQuery строит запрос из аргументов: аргумент из нескольких слов ищется как
+// фраза, с regex аргументы образуют одно выражение
+func searchQuery(args []string, useRegex bool) (search.Query, error) {
+	var query search.Query
+
+	if useRegex {
+		re, err := regexp.Compile(strings.Join(args, " "))
+		if err != nil {
+			return query, err
+		}
+		query.Regex = re
+
+		return query, nil
+	}
+
+	query.Words, query.Phrases = search.ParseArgs(args)
+
+	return query, nil
+}
+
+// chatLabels возвращает псевдонимы чатов по их ID
+func chatLabels(username string, chatRegistry registry.Registry) map[string]string {
+	labels := make(map[string]string)
+
+	chats, err := chatRegistry.List(username)
+	if err != nil {
+		logger.Debug("failed to list chats", zap.Error(err))
+		return labels
+	}
+
+	for _, chat := range chats {
+		if chat.Alias != "" {
+			labels[chat.ID] = chat.Alias
+		}
+	}
+
+	return labels
+}
+
+func printSearchResults(out io.Writer, results []search.Result, labels map[string]string) {
+	before, after := highlightStart, highlightEnd
+	if os.Getenv("NO_COLOR") != "" {
+		before, after = "", ""
+	}
+
+	for n, result := range results {
+		if len(result.Context) == 0 {
+			fmt.Fprintln(out, formatSearchLine(result.Message, search.Highlight(result.Message.Text, result.Matches, before, after), labels))
+			continue
+		}
+
+		if n > 0 {
+			fmt.Fprintln(out, "--")
+		}
+
+		for i, msg := range result.Context {
+			if i == result.Position {
+				fmt.Fprintln(out, "> "+formatSearchLine(msg, search.Highlight(msg.Text, result.Matches, before, after), labels))
+				continue
+			}
+			fmt.Fprintln(out, "  "+formatSearchLine(msg, msg.Text, labels))
+		}
+	}
+}
+
+func formatSearchLine(msg model.Message, text string, labels map[string]string) string {
+	chat := msg.ChatID
+	if label, ok := labels[chat]; ok {
+		chat = label
+	}
+
+	return fmt.Sprintf("%s #%s [%s]: %s", msg.Time.Local().Format(time.DateTime), chat, msg.Username, text)
+}
@@ -0,0 +1,66 @@
+package root
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Mobo140/chat-cli/internal/shellwords"
+)
+
+// replArgs разбирает строку REPL так же, как StartREPL, и отбрасывает имя команды и флаги
+func replArgs(t *testing.T, line string) []string {
+	t.Helper()
+
+	args, err := shellwords.Split(line)
+	if err != nil {
+		t.Fatalf("split %q: %v", line, err)
+	}
+
+	var rest []string
+	for _, arg := range args[1:] {
+		if arg != "--regex" {
+			rest = append(rest, arg)
+		}
+	}
+
+	return rest
+}
+
+func TestSearchQueryKeepsQuotedPhrase(t *testing.T) {
+	query, err := searchQuery(replArgs(t, `search "deploy failed" rollback`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"deploy failed"}; !reflect.DeepEqual(query.Phrases, want) {
+		t.Fatalf("phrases = %q, want %q", query.Phrases, want)
+	}
+	if want := []string{"rollback"}; !reflect.DeepEqual(query.Words, want) {
+		t.Fatalf("words = %q, want %q", query.Words, want)
+	}
+
+	if _, ok := query.Match("rollback after deploy failed"); !ok {
+		t.Fatal("phrase should match adjacent words")
+	}
+	if _, ok := query.Match("failed to deploy, rollback"); ok {
+		t.Fatal("phrase should not match words in another order")
+	}
+}
+
+func TestSearchQueryKeepsRegexBackslashes(t *testing.T) {
+	query, err := searchQuery(replArgs(t, `search --regex 'v\d+\.\d+'`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := query.Regex.String(), `v\d+\.\d+`; got != want {
+		t.Fatalf("regex = %q, want %q", got, want)
+	}
+
+	if _, ok := query.Match("released v1.2"); !ok {
+		t.Fatal("regex should match a version")
+	}
+	if _, ok := query.Match("vdd.d"); ok {
+		t.Fatal("regex should not match letters instead of digits")
+	}
+}
@@ -0,0 +1,132 @@
+package root
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chzyer/readline"
+)
+
+// interruptWindow второй Ctrl+C в пределах окна завершает приложение
+const interruptWindow = 2 * time.Second
+
+// interrupts распределяет сигналы в REPL: Ctrl+C отменяет контекст выполняемой
+// команды, повторный Ctrl+C или SIGTERM завершают REPL штатно, без os.Exit
+type interrupts struct {
+	ctx context.Context
+	rl  *readline.Instance
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	last    time.Time
+	exiting bool
+}
+
+// newInterrupts создаёт диспетчер, отмена ctx завершает REPL так же, как SIGTERM
+func newInterrupts(ctx context.Context, rl *readline.Instance) *interrupts {
+	return &interrupts{ctx: ctx, rl: rl}
+}
+
+// watch обрабатывает сигналы до вызова возвращённой функции
+func (i *interrupts) watch() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-i.ctx.Done():
+				i.exit()
+				return
+			case sig := <-signals:
+				if sig == syscall.SIGTERM {
+					i.exit()
+					continue
+				}
+				i.interrupt()
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// command возвращает контекст очередной команды, finish освобождает его
+func (i *interrupts) command() (ctx context.Context, finish func()) {
+	ctx, cancel := context.WithCancel(i.ctx)
+
+	i.mu.Lock()
+	i.cancel = cancel
+	i.mu.Unlock()
+
+	return ctx, func() {
+		i.mu.Lock()
+		i.cancel = nil
+		i.mu.Unlock()
+
+		cancel()
+	}
+}
+
+// interrupt обрабатывает Ctrl+C: отменяет команду, а второй подряд завершает REPL.
+// Возвращает true, если REPL должен завершиться
+func (i *interrupts) interrupt() bool {
+	i.mu.Lock()
+	now := time.Now()
+	repeated := now.Sub(i.last) < interruptWindow
+	i.last = now
+	cancel := i.cancel
+	i.mu.Unlock()
+
+	if repeated {
+		i.exit()
+		return true
+	}
+
+	if cancel != nil {
+		printer.Printf("^C Cancelled. Press Ctrl+C again to exit.")
+		cancel()
+		return false
+	}
+
+	printer.Printf("^C Press Ctrl+C again or Ctrl+D to exit.")
+
+	return false
+}
+
+// exit отменяет выполняемую команду и прерывает ожидание ввода
+func (i *interrupts) exit() {
+	i.mu.Lock()
+	if i.exiting {
+		i.mu.Unlock()
+		return
+	}
+	i.exiting = true
+	cancel := i.cancel
+	i.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+
+	// Прерывает ожидание ввода в REPL, join или запросе пароля
+	i.rl.Close()
+}
+
+// done сообщает, что REPL пора завершить
+func (i *interrupts) done() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.exiting
+}
@@ -0,0 +1,295 @@
+package root
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/render"
+	"github.com/Mobo140/chat-cli/internal/stream"
+	"github.com/Mobo140/chat-cli/internal/subscription"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newSubscribeCmd(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "subscribe CHAT...",
+		Short: "Receive messages of chats in the background",
+		Long: `Subscribe to one or more chats by ID or alias. Messages of all subscribed chats
+are printed above the prompt, prefixed with the chat name, while other commands keep working.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := account.Current(cmd.Context(), deps.AccountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			renderer, err := outputRenderer(cmd, deps.OutputConfig)
+			if err != nil {
+				logger.Error("invalid output format", zap.Error(err))
+				return
+			}
+
+			labels := chatLabels(username, deps.ChatRegistry)
+			output := newChatOutput(deps.HistoryStore, renderer, printer, false)
+
+			for _, ref := range args {
+				chatID, err := resolveChatID(cmd.Context(), deps.AccountStore, deps.ChatRegistry, ref)
+				if err != nil {
+					logger.Error("failed to resolve chat", zap.String("chat", ref), zap.Error(err))
+					continue
+				}
+
+				label := chatID
+				if alias, ok := labels[chatID]; ok {
+					label = alias
+				}
+
+				err = deps.Subscriptions.Subscribe(chatID, label, username, username, output)
+				if err != nil {
+					logger.Error("failed to subscribe", zap.String("chat", label), zap.Error(err))
+					continue
+				}
+
+				touchChat(cmd.Context(), deps.AccountStore, deps.ChatRegistry, model.Chat{
+					ID:      chatID,
+					Members: []string{username},
+				})
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Subscribed to %s\n", label)
+			}
+		},
+	}
+
+	addFormatFlag(cmd)
+
+	return cmd
+}
+
+func newUnsubscribeCmd(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unsubscribe CHAT... | --all",
+		Short: "Stop receiving messages of chats",
+		Long: `Unsubscribe the current account (or the one given with --as) from chats.
+--all stops the subscriptions of all accounts.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			all, _ := cmd.Flags().GetBool("all")
+
+			if all {
+				for _, info := range deps.Subscriptions.List() {
+					if err := deps.Subscriptions.Unsubscribe(info.Account, info.ChatID); err != nil {
+						logger.Error("failed to unsubscribe", zap.String("chat", info.Label), zap.Error(err))
+						continue
+					}
+
+					fmt.Fprintf(cmd.OutOrStdout(), "Unsubscribed %s from %s\n", info.Account, info.Label)
+				}
+				return
+			}
+
+			if len(args) == 0 {
+				logger.Error("no chats given, pass chat IDs or aliases or --all")
+				return
+			}
+
+			username, err := account.Current(cmd.Context(), deps.AccountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			for _, ref := range args {
+				chatID, err := resolveChatID(cmd.Context(), deps.AccountStore, deps.ChatRegistry, ref)
+				if err != nil {
+					logger.Error("failed to resolve chat", zap.String("chat", ref), zap.Error(err))
+					continue
+				}
+
+				if err := deps.Subscriptions.Unsubscribe(username, chatID); err != nil {
+					logger.Error("failed to unsubscribe", zap.String("chat", ref), zap.Error(err))
+					continue
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Unsubscribed from %s\n", ref)
+			}
+		},
+	}
+
+	cmd.Flags().Bool("all", false, "Unsubscribe from all chats")
+
+	return cmd
+}
+
+func newMuteCmd(deps Deps, muted bool) *cobra.Command {
+	use, short, done := "mute CHAT...", "Hide messages of subscribed chats", "Muted"
+	if !muted {
+		use, short, done = "unmute CHAT...", "Show messages of muted chats again", "Unmuted"
+	}
+
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long: short + `.
+Messages of muted chats are still received and saved to the history.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := account.Current(cmd.Context(), deps.AccountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			for _, ref := range args {
+				chatID, err := resolveChatID(cmd.Context(), deps.AccountStore, deps.ChatRegistry, ref)
+				if err != nil {
+					logger.Error("failed to resolve chat", zap.String("chat", ref), zap.Error(err))
+					continue
+				}
+
+				if err := deps.Subscriptions.SetMuted(username, chatID, muted); err != nil {
+					logger.Error("failed to change chat output", zap.String("chat", ref), zap.Error(err))
+					continue
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", done, ref)
+			}
+		},
+	}
+}
+
+func newSubscriptionsCmd(deps Deps) *cobra.Command {
+	return &cobra.Command{
+		Use:   "subscriptions",
+		Short: "List chat subscriptions with stream state",
+		Run: func(cmd *cobra.Command, args []string) {
+			subs := deps.Subscriptions.List()
+
+			if len(subs) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No subscriptions. Use subscribe CHAT to add one.")
+				return
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CHAT\tID\tACCOUNT\tSTATE\tMESSAGES\tLAST ACTIVITY\tMUTED\tERROR")
+
+			for _, info := range subs {
+				muted := "no"
+				if info.Muted {
+					muted = "yes"
+				}
+
+				lastError := ""
+				if info.Err != nil {
+					lastError = info.Err.Error()
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+					info.Label,
+					info.ChatID,
+					info.Account,
+					info.State,
+					info.Messages,
+					formatTime(info.LastActivity),
+					muted,
+					valueOrDash(truncate(lastError, 60)))
+			}
+
+			w.Flush()
+		},
+	}
+}
+
+var _ subscription.Handler = (*chatOutput)(nil)
+
+// chatOutput сохраняет сообщения подписок в историю и выводит их с меткой чата
+type chatOutput struct {
+	historyStore history.Store
+	renderer     render.Renderer
+	out          io.Writer
+	// logEvents события потока пишутся в лог, а не выводятся строкой состояния
+	logEvents bool
+}
+
+func newChatOutput(
+	historyStore history.Store,
+	renderer render.Renderer,
+	out io.Writer,
+	logEvents bool,
+) *chatOutput {
+	return &chatOutput{
+		historyStore: historyStore,
+		renderer:     renderer,
+		out:          out,
+		logEvents:    logEvents,
+	}
+}
+
+func (o *chatOutput) Message(info subscription.Info, msg *chat.Message) {
+	m := model.Message{
+		ChatID:   msg.ChatID,
+		Username: msg.Username,
+		Text:     msg.Text,
+		Time:     msg.Time,
+	}
+	// Время сервера неизвестно, используем время получения
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+
+	if err := o.historyStore.Append(info.Account, m); err != nil {
+		logger.Warn("failed to save message to history", zap.String("chat_id", m.ChatID), zap.Error(err))
+	}
+
+	if info.Muted {
+		return
+	}
+
+	if err := o.renderer.Message(o.out, info.Label, m); err != nil {
+		logger.Debug("failed to print message", zap.Error(err))
+	}
+}
+
+// Event показывает разрывы и восстановление потока чата
+func (o *chatOutput) Event(info subscription.Info, e stream.Event) {
+	if o.logEvents {
+		logStreamEvent(info.ChatID, info.Username, e)
+		return
+	}
+
+	if e.State == stream.StateConnecting || (e.State == stream.StateConnected && e.Attempt == 0) {
+		return
+	}
+	if e.State == stream.StateStopped && e.Err == nil {
+		return
+	}
+
+	if err := o.renderer.Status(o.out, info.Label, stream.Describe(e)); err != nil {
+		logger.Debug("failed to print stream status", zap.Error(err))
+	}
+}
+
+// addFormatFlag добавляет флаг --format для команд, выводящих входящие сообщения
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("format", "", fmt.Sprintf("Message output format: %s (default from CHAT_CLI_OUTPUT_FORMAT)",
+		strings.Join(render.Formats, ", ")))
+}
+
+// outputRenderer выбирает формат из флага --format или настроек
+func outputRenderer(cmd *cobra.Command, outputConfig config.OutputConfig) (render.Renderer, error) {
+	format, _ := cmd.Flags().GetString("format")
+	if format == "" {
+		format = outputConfig.Format()
+	}
+
+	return render.New(format, outputConfig.Color())
+}
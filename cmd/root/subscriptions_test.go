@@ -0,0 +1,45 @@
+package root
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/render"
+	"github.com/Mobo140/chat-cli/internal/subscription"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestChatOutputKeepsServerTime(t *testing.T) {
+	logger.Init(zapcore.NewNopCore())
+
+	renderer, err := render.New(render.FormatPlain, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "messages"), nil)
+	out := newChatOutput(store, renderer, &bytes.Buffer{}, false)
+	info := subscription.Info{ChatID: "1", Label: "1", Account: "bob"}
+
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	out.Message(info, &chat.Message{ChatID: "1", Username: "alice", Text: "old", Time: sent})
+
+	before := time.Now()
+	out.Message(info, &chat.Message{ChatID: "1", Username: "alice", Text: "new"})
+
+	times := storedTimes(t, store, "bob", "1")
+	if len(times) != 2 {
+		t.Fatalf("stored %d messages, want 2", len(times))
+	}
+	if !times[0].Equal(sent) {
+		t.Errorf("message with server time stored at %v, want %v", times[0], sent)
+	}
+	if times[1].Before(before) {
+		t.Errorf("message without server time stored at %v, want receive time", times[1])
+	}
+}
@@ -0,0 +1,203 @@
+package root
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/clients"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/chat-cli/internal/token"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// accessCheckEndpoint метод, доступ к которому проверяет whoami
+const accessCheckEndpoint = "/chat_v1.ChatV1/ConnectChat"
+
+type tokenStatus struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Remaining string    `json:"remaining"`
+	Expired   bool      `json:"expired"`
+}
+
+type whoamiInfo struct {
+	Username      string       `json:"username"`
+	Role          string       `json:"role"`
+	AccessToken   *tokenStatus `json:"access_token,omitempty"`
+	RefreshToken  *tokenStatus `json:"refresh_token,omitempty"`
+	LastRefreshAt *time.Time   `json:"last_refresh_at,omitempty"`
+	SessionFile   string       `json:"session_file,omitempty"`
+	ServerAccepts *bool        `json:"server_accepts,omitempty"`
+	ServerError   string       `json:"server_error,omitempty"`
+}
+
+func newWhoamiCmd(authClient clients.AuthServiceClient, sessionStore session.Store, accountStore account.Store) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the current account and session status",
+		Run: func(cmd *cobra.Command, args []string) {
+			output, _ := cmd.Flags().GetString("output")
+			offline, _ := cmd.Flags().GetBool("offline")
+
+			if output != "text" && output != "json" {
+				logger.Error("unsupported output format", zap.String("output", output))
+				return
+			}
+
+			username, err := account.Current(cmd.Context(), accountStore)
+			if err != nil {
+				logger.Error("failed to get current account", zap.Error(err))
+				return
+			}
+
+			s, err := sessionStore.Load(username)
+			if err != nil {
+				logger.Error("failed to load session", zap.Error(err))
+				return
+			}
+
+			var (
+				accepted  *bool
+				serverErr string
+			)
+			if !offline {
+				ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+				defer cancel()
+
+				err := checkAccessToken(ctx, authClient, s.AccessToken, time.Now())
+				ok := err == nil
+				if !ok {
+					serverErr = err.Error()
+				}
+				accepted = &ok
+			}
+
+			info := newWhoamiInfo(s, time.Now())
+			info.ServerAccepts = accepted
+			info.ServerError = serverErr
+			if locator, ok := sessionStore.(session.Locator); ok {
+				info.SessionFile = locator.Path(username)
+			}
+
+			if output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(info); err != nil {
+					logger.Error("failed to encode session info", zap.Error(err))
+				}
+				return
+			}
+
+			printWhoami(cmd.OutOrStdout(), info)
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	cmd.Flags().Bool("offline", false, "Do not check the session against the server")
+
+	return cmd
+}
+
+// checkAccessToken проверяет сохранённый access token, не выпуская новый и не
+// меняя сессию: whoami только показывает состояние
+func checkAccessToken(ctx context.Context, authClient clients.AuthServiceClient, accessToken string, now time.Time) error {
+	claims, err := token.ParseClaims(accessToken)
+	if err != nil {
+		return fmt.Errorf("no valid access token stored: %w", err)
+	}
+	if claims.Expired(now) {
+		return errors.New("access token expired, it is refreshed by the next command")
+	}
+
+	err = authClient.CheckAccess(ctx, accessToken, accessCheckEndpoint)
+	// Отказ в доступе к методу означает, что сам токен сервер принял
+	if status.Code(err) == codes.PermissionDenied {
+		return nil
+	}
+
+	return err
+}
+
+func newWhoamiInfo(s *Session, now time.Time) whoamiInfo {
+	info := whoamiInfo{Username: s.Username}
+
+	if !s.RefreshedAt.IsZero() {
+		refreshedAt := s.RefreshedAt
+		info.LastRefreshAt = &refreshedAt
+	}
+
+	if claims, err := token.ParseClaims(s.AccessToken); err == nil {
+		info.Role = claims.Role
+		info.AccessToken = newTokenStatus(claims, now)
+	}
+
+	if claims, err := token.ParseClaims(s.RefreshToken); err == nil {
+		if info.Role == "" {
+			info.Role = claims.Role
+		}
+		info.RefreshToken = newTokenStatus(claims, now)
+	}
+
+	return info
+}
+
+func newTokenStatus(claims *token.Claims, now time.Time) *tokenStatus {
+	remaining := claims.ExpiresAtTime().Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &tokenStatus{
+		ExpiresAt: claims.ExpiresAtTime(),
+		Remaining: remaining.Round(time.Second).String(),
+		Expired:   claims.Expired(now),
+	}
+}
+
+func printWhoami(w io.Writer, info whoamiInfo) {
+	fmt.Fprintf(w, "User:          %s\n", info.Username)
+	fmt.Fprintf(w, "Role:          %s\n", valueOrDash(info.Role))
+	fmt.Fprintf(w, "Access token:  %s\n", formatTokenStatus(info.AccessToken))
+	fmt.Fprintf(w, "Refresh token: %s\n", formatTokenStatus(info.RefreshToken))
+
+	lastRefresh := "-"
+	if info.LastRefreshAt != nil {
+		lastRefresh = info.LastRefreshAt.Format(time.DateTime)
+	}
+	fmt.Fprintf(w, "Last refresh:  %s\n", lastRefresh)
+	fmt.Fprintf(w, "Session file:  %s\n", valueOrDash(info.SessionFile))
+
+	server := "not checked"
+	switch {
+	case info.ServerAccepts == nil:
+	case *info.ServerAccepts:
+		server = "session accepted"
+	default:
+		server = "session rejected: " + info.ServerError
+	}
+	fmt.Fprintf(w, "Server:        %s\n", server)
+}
+
+func formatTokenStatus(status *tokenStatus) string {
+	if status == nil {
+		return "invalid"
+	}
+
+	return formatExpiry(status.ExpiresAt, time.Now())
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
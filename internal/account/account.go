@@ -0,0 +1,36 @@
+package account
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNoActiveAccount = errors.New("no active account, please login first")
+
+// Store хранит имя активного аккаунта между запусками
+type Store interface {
+	Active() (string, error)
+	SetActive(username string) error
+	ClearActive() error
+}
+
+type usernameKey struct{}
+
+// WithUsername переопределяет аккаунт для одной команды (флаг --as)
+func WithUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey{}, username)
+}
+
+func UsernameFromContext(ctx context.Context) (string, bool) {
+	username, ok := ctx.Value(usernameKey{}).(string)
+	return username, ok && username != ""
+}
+
+// Current возвращает аккаунт из контекста или активный аккаунт
+func Current(ctx context.Context, store Store) (string, error) {
+	if username, ok := UsernameFromContext(ctx); ok {
+		return username, nil
+	}
+
+	return store.Active()
+}
@@ -0,0 +1,61 @@
+package account
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+const filePerm = 0o600
+
+var _ Store = (*fileStore)(nil)
+
+type fileStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+func NewFileStore(path string) *fileStore {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Active() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNoActiveAccount
+	}
+	if err != nil {
+		return "", err
+	}
+
+	username := strings.TrimSpace(string(data))
+	if username == "" {
+		return "", ErrNoActiveAccount
+	}
+
+	return username, nil
+}
+
+func (s *fileStore) SetActive(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return os.WriteFile(s.path, []byte(username+"\n"), filePerm)
+}
+
+func (s *fileStore) ClearActive() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,40 @@
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+const factor = 2
+
+// Backoff экспоненциальная задержка между попытками со случайным разбросом
+type Backoff struct {
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+func New(min, max time.Duration) *Backoff {
+	return &Backoff{min: min, max: max}
+}
+
+// Next возвращает задержку перед следующей попыткой: половина текущего
+// интервала плюс случайная добавка, чтобы клиенты не ретраили синхронно
+func (b *Backoff) Next() time.Duration {
+	if b.current == 0 {
+		b.current = b.min
+	} else {
+		b.current *= factor
+	}
+	if b.current > b.max {
+		b.current = b.max
+	}
+
+	half := b.current / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (b *Backoff) Reset() {
+	b.current = 0
+}
@@ -3,20 +3,28 @@ package auth
 import (
 	"context"
 
+	descAccess "github.com/Mobo140/auth/pkg/access_v1"
 	descAuth "github.com/Mobo140/auth/pkg/auth_v1"
 	"github.com/Mobo140/chat-cli/internal/clients"
 	"github.com/Mobo140/platform_common/pkg/logger"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	authHeader = "authorization"
+	authPrefix = "Bearer "
 )
 
 var _ clients.AuthServiceClient = (*client)(nil)
 
 type client struct {
-	authClient descAuth.AuthV1Client
+	authClient   descAuth.AuthV1Client
+	accessClient descAccess.AccessV1Client
 }
 
-func NewAuthClient(authClient descAuth.AuthV1Client) *client {
-	return &client{authClient: authClient}
+func NewAuthClient(authClient descAuth.AuthV1Client, accessClient descAccess.AccessV1Client) *client {
+	return &client{authClient: authClient, accessClient: accessClient}
 }
 
 func (c *client) Login(ctx context.Context, name string, password string) (string, error) {
@@ -57,3 +65,17 @@ func (c *client) GetRefreshToken(ctx context.Context, refreshToken string) (stri
 
 	return resp.GetRefreshToken(), nil
 }
+
+func (c *client) CheckAccess(ctx context.Context, accessToken string, endpoint string) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, authHeader, authPrefix+accessToken)
+
+	_, err := c.accessClient.Check(ctx, &descAccess.CheckRequest{
+		EndpointAddress: endpoint,
+	})
+	if err != nil {
+		logger.Debug("access check failed", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
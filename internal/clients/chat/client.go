@@ -2,7 +2,6 @@ package chat
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"strconv"
 
@@ -80,7 +79,8 @@ func (c *client) SendMessage(ctx context.Context, message *Message) error {
 	return nil
 }
 
-func (c *client) ConnectChat(ctx context.Context, chatID string, username string) error {
+// ConnectChat передаёт входящие сообщения чата в handler до закрытия потока или отмены ctx
+func (c *client) ConnectChat(ctx context.Context, chatID string, username string, handler func(*Message)) error {
 	stream, err := c.chatClient.ConnectChat(ctx, &descChat.ConnectChatRequest{
 		ChatId:   chatID,
 		Username: username,
@@ -100,7 +100,11 @@ func (c *client) ConnectChat(ctx context.Context, chatID string, username string
 			return err
 		}
 
-		fmt.Printf("\n[%s]: %s\n", msg.GetFrom(), msg.GetText())
+		handler(&Message{
+			ChatID:   chatID,
+			Text:     msg.GetText(),
+			Username: msg.GetFrom(),
+		})
 	}
 
 	return nil
@@ -1,7 +1,12 @@
 package chat
 
+import "time"
+
 type Message struct {
 	ChatID   string
 	Text     string
 	Username string
+	// Time время сообщения на сервере. chat_v1 его не передаёт, поэтому для
+	// полученных сообщений оно нулевое, и клиент берёт время получения
+	Time time.Time
 }
@@ -10,11 +10,13 @@ type ChatServiceClient interface {
 	Create(ctx context.Context, usernames []string) (string, error)
 	Delete(ctx context.Context, chatID string) error
 	SendMessage(ctx context.Context, message *chat.Message) error
-	ConnectChat(ctx context.Context, chatID string, username string) error
+	ConnectChat(ctx context.Context, chatID string, username string, handler func(*chat.Message)) error
 }
 
 type AuthServiceClient interface {
 	Login(ctx context.Context, name string, password string) (string, error)
 	GetAccessToken(ctx context.Context, refreshToken string) (string, error)
 	GetRefreshToken(ctx context.Context, accessToken string) (string, error)
+	// CheckAccess проверяет access token на сервере, ничего не выпуская и не продлевая
+	CheckAccess(ctx context.Context, accessToken string, endpoint string) error
 }
@@ -1,6 +1,10 @@
 package config
 
-import "github.com/joho/godotenv"
+import (
+	"time"
+
+	"github.com/joho/godotenv"
+)
 
 type ChatClientConfig interface {
 	Address() string
@@ -14,6 +18,38 @@ type JaegerConfig interface {
 	Address() string
 }
 
+type SessionConfig interface {
+	Passphrase() string
+}
+
+type TokenConfig interface {
+	AccessTokenMargin() time.Duration
+	RefreshTokenMargin() time.Duration
+}
+
+type StreamConfig interface {
+	ReconnectMinDelay() time.Duration
+	ReconnectMaxDelay() time.Duration
+	// StallTimeout время без сообщений, после которого поток переоткрывается, 0 отключает проверку
+	StallTimeout() time.Duration
+}
+
+type OutputConfig interface {
+	// Format формат вывода входящих сообщений: plain, color или json
+	Format() string
+	// Color false, если цвета отключены через NO_COLOR
+	Color() bool
+}
+
+type LoginConfig interface {
+	RefreshToken() string
+	CredentialsFile() string
+}
+
+type RoleConfig interface {
+	RoleName(value string) string
+}
+
 func Load(path string) error {
 	err := godotenv.Load(path)
 	if err != nil {
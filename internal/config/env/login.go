@@ -0,0 +1,28 @@
+package env
+
+import "os"
+
+const (
+	refreshTokenEnv    = "CHAT_CLI_REFRESH_TOKEN"
+	credentialsFileEnv = "CHAT_CLI_CREDENTIALS_FILE"
+)
+
+type loginConfig struct {
+	refreshToken    string
+	credentialsFile string
+}
+
+func NewLoginConfig() (*loginConfig, error) {
+	return &loginConfig{
+		refreshToken:    os.Getenv(refreshTokenEnv),
+		credentialsFile: os.Getenv(credentialsFileEnv),
+	}, nil
+}
+
+func (c *loginConfig) RefreshToken() string {
+	return c.refreshToken
+}
+
+func (c *loginConfig) CredentialsFile() string {
+	return c.credentialsFile
+}
@@ -0,0 +1,38 @@
+package env
+
+import (
+	"os"
+)
+
+const (
+	outputFormatEnv = "CHAT_CLI_OUTPUT_FORMAT"
+	// noColorEnv общепринятая переменная отключения цветов, см. no-color.org
+	noColorEnv = "NO_COLOR"
+
+	defaultOutputFormat = "color"
+)
+
+type outputConfig struct {
+	format string
+	color  bool
+}
+
+func NewOutputConfig() (*outputConfig, error) {
+	format := os.Getenv(outputFormatEnv)
+	if len(format) == 0 {
+		format = defaultOutputFormat
+	}
+
+	return &outputConfig{
+		format: format,
+		color:  len(os.Getenv(noColorEnv)) == 0,
+	}, nil
+}
+
+func (c *outputConfig) Format() string {
+	return c.format
+}
+
+func (c *outputConfig) Color() bool {
+	return c.color
+}
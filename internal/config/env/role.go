@@ -0,0 +1,47 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	rolesEnv = "CHAT_CLI_ROLES"
+
+	// Значения роли в токенах сервиса auth
+	defaultRoles = "0=user,1=admin"
+)
+
+type roleConfig struct {
+	names map[string]string
+}
+
+// NewRoleConfig читает соответствие значений claim role именам ролей
+// в формате "0=user,1=admin"
+func NewRoleConfig() (*roleConfig, error) {
+	value := os.Getenv(rolesEnv)
+	if len(value) == 0 {
+		value = defaultRoles
+	}
+
+	names := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		role, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || role == "" || name == "" {
+			return nil, fmt.Errorf("invalid %s entry: %q", rolesEnv, pair)
+		}
+		names[strings.TrimSpace(role)] = strings.TrimSpace(name)
+	}
+
+	return &roleConfig{names: names}, nil
+}
+
+// RoleName возвращает имя роли или само значение, если оно не описано
+func (c *roleConfig) RoleName(value string) string {
+	if name, ok := c.names[value]; ok {
+		return name
+	}
+
+	return value
+}
@@ -0,0 +1,20 @@
+package env
+
+import "os"
+
+const (
+	sessionPassphraseEnv = "CHAT_CLI_SESSION_PASSPHRASE"
+)
+
+type sessionConfig struct {
+	passphrase string
+}
+
+// NewSessionConfig пустая passphrase означает хранение сессий без шифрования
+func NewSessionConfig() (*sessionConfig, error) {
+	return &sessionConfig{passphrase: os.Getenv(sessionPassphraseEnv)}, nil
+}
+
+func (c *sessionConfig) Passphrase() string {
+	return c.passphrase
+}
@@ -0,0 +1,63 @@
+package env
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	reconnectMinDelayEnv = "CHAT_STREAM_RECONNECT_MIN_DELAY"
+	reconnectMaxDelayEnv = "CHAT_STREAM_RECONNECT_MAX_DELAY"
+	stallTimeoutEnv      = "CHAT_STREAM_STALL_TIMEOUT"
+
+	defaultReconnectMinDelay = time.Second
+	defaultReconnectMaxDelay = 30 * time.Second
+	// defaultStallTimeout поток без сообщений переоткрывается молча, поэтому
+	// тихим чатам проверка не мешает
+	defaultStallTimeout = 10 * time.Minute
+)
+
+type streamConfig struct {
+	reconnectMinDelay time.Duration
+	reconnectMaxDelay time.Duration
+	stallTimeout      time.Duration
+}
+
+func NewStreamConfig() (*streamConfig, error) {
+	reconnectMinDelay, err := durationFromEnv(reconnectMinDelayEnv, defaultReconnectMinDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	reconnectMaxDelay, err := durationFromEnv(reconnectMaxDelayEnv, defaultReconnectMaxDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	if reconnectMinDelay == 0 || reconnectMaxDelay < reconnectMinDelay {
+		return nil, fmt.Errorf("%s must be positive and not greater than %s", reconnectMinDelayEnv, reconnectMaxDelayEnv)
+	}
+
+	stallTimeout, err := durationFromEnv(stallTimeoutEnv, defaultStallTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamConfig{
+		reconnectMinDelay: reconnectMinDelay,
+		reconnectMaxDelay: reconnectMaxDelay,
+		stallTimeout:      stallTimeout,
+	}, nil
+}
+
+func (c *streamConfig) ReconnectMinDelay() time.Duration {
+	return c.reconnectMinDelay
+}
+
+func (c *streamConfig) ReconnectMaxDelay() time.Duration {
+	return c.reconnectMaxDelay
+}
+
+func (c *streamConfig) StallTimeout() time.Duration {
+	return c.stallTimeout
+}
@@ -0,0 +1,62 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	accessTokenMarginEnv  = "ACCESS_TOKEN_REFRESH_MARGIN"
+	refreshTokenMarginEnv = "REFRESH_TOKEN_REFRESH_MARGIN"
+
+	defaultAccessTokenMargin  = time.Minute
+	defaultRefreshTokenMargin = time.Hour
+)
+
+type tokenConfig struct {
+	accessTokenMargin  time.Duration
+	refreshTokenMargin time.Duration
+}
+
+func NewTokenConfig() (*tokenConfig, error) {
+	accessTokenMargin, err := durationFromEnv(accessTokenMarginEnv, defaultAccessTokenMargin)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenMargin, err := durationFromEnv(refreshTokenMarginEnv, defaultRefreshTokenMargin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenConfig{
+		accessTokenMargin:  accessTokenMargin,
+		refreshTokenMargin: refreshTokenMargin,
+	}, nil
+}
+
+func (c *tokenConfig) AccessTokenMargin() time.Duration {
+	return c.accessTokenMargin
+}
+
+func (c *tokenConfig) RefreshTokenMargin() time.Duration {
+	return c.refreshTokenMargin
+}
+
+func durationFromEnv(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if len(value) == 0 {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s must not be negative", key)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,66 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/chzyer/readline"
+)
+
+var _ io.Writer = (*Console)(nil)
+
+// Console единый вывод приложения: потоки чатов, логи и результаты фоновых
+// задач пишутся по очереди, а пока REPL ждёт ввода, строки печатаются над
+// приглашением и readline заново выводит набираемый текст
+type Console struct {
+	mu  sync.Mutex
+	out io.Writer
+	rl  *readline.Instance
+}
+
+func New(out io.Writer) *Console {
+	return &Console{out: out}
+}
+
+// Attach направляет вывод через readline до вызова возвращённой функции
+func (c *Console) Attach(rl *readline.Instance) (detach func()) {
+	c.mu.Lock()
+	c.rl = rl
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		c.rl = nil
+		c.mu.Unlock()
+	}
+}
+
+func (c *Console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rl != nil {
+		// Вне чтения строки readline пишет напрямую, во время чтения стирает
+		// приглашение, печатает и перерисовывает его вместе с вводом
+		return c.rl.Stdout().Write(p)
+	}
+
+	return c.out.Write(p)
+}
+
+// Printf печатает одну или несколько целых строк
+func (c *Console) Printf(format string, args ...any) {
+	s := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+
+	c.Write([]byte(s))
+}
+
+// Sync нужен логгеру, вывод не буферизуется
+func (c *Console) Sync() error {
+	return nil
+}
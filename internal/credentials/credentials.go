@@ -0,0 +1,108 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrNotFound = errors.New("credentials not found")
+
+// Profile учётные данные для автоматического входа без участия пользователя
+type Profile struct {
+	Username     string `json:"username"`
+	PasswordFile string `json:"password_file"`
+}
+
+// Store источник учётных данных по имени профиля или пользователя
+type Store interface {
+	Profile(name string) (*Profile, error)
+	ByUsername(username string) (*Profile, error)
+}
+
+// Password читает пароль из файла профиля
+func (p *Profile) Password() (string, error) {
+	data, err := os.ReadFile(p.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", p.PasswordFile)
+	}
+
+	return password, nil
+}
+
+var _ Store = (*fileStore)(nil)
+
+// fileStore читает JSON-файл вида {"profiles": {"bot": {"username": "...", "password_file": "..."}}}.
+// Файл перечитывается при каждом обращении, чтобы подхватывать изменения
+type fileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *fileStore {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Profile(name string) (*Profile, error) {
+	profiles, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return profile, nil
+}
+
+func (s *fileStore) ByUsername(username string) (*Profile, error) {
+	profiles, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Username == username {
+			return profile, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *fileStore) load() (map[string]*Profile, error) {
+	if s.path == "" {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Profiles map[string]*Profile `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+
+	for name, profile := range file.Profiles {
+		if profile.Username == "" {
+			profile.Username = name
+		}
+	}
+
+	return file.Profiles, nil
+}
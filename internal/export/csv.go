@@ -0,0 +1,31 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+// csvHeader совпадает с JSON-именами полей model.Message
+var csvHeader = []string{"time", "chat_id", "username", "text"}
+
+type csvExporter struct{}
+
+func (e *csvExporter) Export(w io.Writer, t Transcript) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, msg := range t.Messages {
+		record := []string{msg.Time.Format(time.RFC3339Nano), msg.ChatID, msg.Username, msg.Text}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
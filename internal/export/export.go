@@ -0,0 +1,108 @@
+package export
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "md"
+	FormatCSV      = "csv"
+	FormatHTML     = "html"
+)
+
+// Formats поддерживаемые форматы экспорта
+var Formats = []string{FormatJSON, FormatJSONL, FormatMarkdown, FormatCSV, FormatHTML}
+
+// Transcript сообщения одного чата в хронологическом порядке
+type Transcript struct {
+	Title      string
+	ExportedAt time.Time
+	Messages   []model.Message
+}
+
+// Exporter записывает расшифровку чата в своём формате
+type Exporter interface {
+	Export(w io.Writer, t Transcript) error
+}
+
+func New(format string) (Exporter, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonExporter{}, nil
+	case FormatJSONL:
+		return &jsonLinesExporter{}, nil
+	case FormatMarkdown:
+		return &markdownExporter{}, nil
+	case FormatCSV:
+		return &csvExporter{}, nil
+	case FormatHTML:
+		return &htmlExporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Pseudonymize заменяет имена пользователей на user1, user2... в порядке
+// первого появления, в том числе упоминания имён в тексте
+func Pseudonymize(messages []model.Message) []model.Message {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		if _, ok := names[msg.Username]; !ok {
+			names[msg.Username] = fmt.Sprintf("user%d", len(names)+1)
+		}
+	}
+
+	result := make([]model.Message, len(messages))
+	for i, msg := range messages {
+		msg.Username = names[msg.Username]
+		msg.Text = replaceNames(msg.Text, names)
+		result[i] = msg
+	}
+
+	return result
+}
+
+// replaceNames заменяет имена, стоящие в тексте отдельными словами
+func replaceNames(text string, names map[string]string) string {
+	fields := strings.FieldsFunc(text, isNameSeparator)
+	if len(fields) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	rest := text
+	for _, field := range fields {
+		i := strings.Index(rest, field)
+		b.WriteString(rest[:i])
+
+		if alias, ok := names[field]; ok {
+			b.WriteString(alias)
+		} else {
+			b.WriteString(field)
+		}
+		rest = rest[i+len(field):]
+	}
+	b.WriteString(rest)
+
+	return b.String()
+}
+
+func isNameSeparator(r rune) bool {
+	return strings.ContainsRune(" \t\r\n.,:;!?()[]{}\"'<>@/", r)
+}
+
+// UserHue возвращает постоянный для пользователя оттенок цвета от 0 до 359
+func UserHue(username string) int {
+	h := fnv.New32a()
+	h.Write([]byte(username))
+
+	return int(h.Sum32() % 360)
+}
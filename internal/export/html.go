@@ -0,0 +1,50 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"hue":  UserHue,
+	"date": func(t time.Time) string { return t.Local().Format(time.DateOnly) },
+	"time": func(t time.Time) string { return t.Local().Format(time.TimeOnly) },
+	"iso":  func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1rem; }
+h2 { font-size: 1rem; color: #666; margin: 1.5rem 0 .5rem; }
+.msg { display: flex; gap: .75rem; padding: .25rem 0; }
+.time { color: #999; font-variant-numeric: tabular-nums; white-space: nowrap; }
+.user { font-weight: 600; white-space: nowrap; }
+.text { white-space: pre-wrap; overflow-wrap: anywhere; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p>Exported {{iso .ExportedAt}}, {{len .Messages}} messages</p>
+</header>
+{{- $day := ""}}
+{{- range .Messages}}
+{{- if ne (date .Time) $day}}{{$day = date .Time}}
+<h2>{{$day}}</h2>
+{{- end}}
+<div class="msg"><time class="time" datetime="{{iso .Time}}">{{time .Time}}</time><span class="user" style="color: hsl({{hue .Username}}, 65%, 38%)">{{.Username}}</span><span class="text">{{.Text}}</span></div>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlExporter struct{}
+
+// Export создаёт самодостаточную страницу без внешних ресурсов
+func (e *htmlExporter) Export(w io.Writer, t Transcript) error {
+	return htmlTemplate.Execute(w, t)
+}
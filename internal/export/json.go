@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type jsonExporter struct{}
+
+// Export записывает массив сообщений в формате model.Message
+func (e *jsonExporter) Export(w io.Writer, t Transcript) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	if t.Messages == nil {
+		return encoder.Encode([]struct{}{})
+	}
+
+	return encoder.Encode(t.Messages)
+}
+
+type jsonLinesExporter struct{}
+
+// Export записывает по одному сообщению в строке
+func (e *jsonLinesExporter) Export(w io.Writer, t Transcript) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for _, msg := range t.Messages {
+		if err := encoder.Encode(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+type markdownExporter struct{}
+
+// Export группирует сообщения по дням, многострочные сообщения сохраняют переносы
+func (e *markdownExporter) Export(w io.Writer, t Transcript) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "# %s\n\n", markdownEscaper.Replace(t.Title))
+	fmt.Fprintf(out, "_Exported %s, %d messages_\n", t.ExportedAt.Format(time.DateTime), len(t.Messages))
+
+	var day string
+	for _, msg := range t.Messages {
+		local := msg.Time.Local()
+
+		if d := local.Format(time.DateOnly); d != day {
+			day = d
+			fmt.Fprintf(out, "\n## %s\n\n", day)
+		}
+
+		lines := strings.Split(msg.Text, "\n")
+		for i, line := range lines {
+			lines[i] = markdownEscaper.Replace(line)
+		}
+
+		fmt.Fprintf(out, "**%s** `%s`  \n%s\n\n",
+			markdownEscaper.Replace(msg.Username),
+			local.Format(time.TimeOnly),
+			strings.Join(lines, "  \n"))
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,115 @@
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	FilePerm   = 0o600
+	LockSuffix = ".lock"
+	TempMarker = ".tmp-"
+
+	lockTimeout    = 5 * time.Second
+	lockRetryDelay = 20 * time.Millisecond
+)
+
+var (
+	ErrLockTimeout = errors.New("timed out waiting for file lock")
+	ErrInvalidName = errors.New("name must not be empty, contain path separators or be . or ..")
+)
+
+// ValidName проверяет, что имя из пользовательского ввода можно использовать
+// как часть имени файла, не выходя за пределы каталога
+func ValidName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("%q: %w", name, ErrInvalidName)
+	}
+
+	return nil
+}
+
+// Lock ждёт блокировку <path>.lock не дольше lockTimeout и возвращает функцию её снятия.
+// Разделяемая блокировка используется для чтения, эксклюзивная для записи
+func Lock(path string, shared bool) (func(), error) {
+	lock := flock.New(path+LockSuffix, flock.SetPermissions(FilePerm))
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	var (
+		ok  bool
+		err error
+	)
+	if shared {
+		ok, err = lock.TryRLockContext(ctx, lockRetryDelay)
+	} else {
+		ok, err = lock.TryLockContext(ctx, lockRetryDelay)
+	}
+	if errors.Is(err, context.DeadlineExceeded) || (err == nil && !ok) {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), ErrLockTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", filepath.Base(path), err)
+	}
+
+	return func() { lock.Unlock() }, nil
+}
+
+// RemoveStaleLock удаляет файл блокировки, если его никто не держит. Файл
+// удаляется под блокировкой, чтобы никто не захватил его в момент удаления
+func RemoveStaleLock(lockPath string) error {
+	if _, err := os.Stat(lockPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	lock := flock.New(lockPath, flock.SetPermissions(FilePerm))
+
+	ok, err := lock.TryLock()
+	if err != nil || !ok {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := os.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// WriteFileAtomic пишет данные во временный файл и переименовывает его,
+// чтобы читатели никогда не увидели частично записанный файл
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+TempMarker+"*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err := tmp.Chmod(FilePerm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
@@ -0,0 +1,526 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	segmentExt    = ".jsonl"
+	segmentLayout = "2006-01-02"
+	dirPerm       = 0o700
+
+	// echoWindow в пределах окна наше отправленное сообщение, пришедшее из потока
+	// чата, считается копией уже сохранённого при отправке
+	echoWindow = 10 * time.Second
+	// tailSize сколько байт с конца сегмента читается для поиска копий
+	tailSize     = 16 << 10
+	maxLineBytes = 1 << 20
+)
+
+var _ Store = (*fileStore)(nil)
+
+// fileStore хранит историю в файлах <dir>/<username>/<chat_id>/<YYYY-MM-DD>.jsonl:
+// сообщения дописываются в сегмент своего дня (UTC), а выборка по времени
+// читает только сегменты нужных дней, начиная с самого нового
+type fileStore struct {
+	dir     string
+	indexer Indexer
+}
+
+// NewFileStore создаёт хранилище истории, indexer может быть nil
+func NewFileStore(dir string, indexer Indexer) *fileStore {
+	return &fileStore{dir: dir, indexer: indexer}
+}
+
+func (s *fileStore) Append(username string, messages ...model.Message) error {
+	for _, msg := range messages {
+		if err := s.append(username, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *fileStore) append(username string, msg model.Message) error {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	ref, ok, err := s.write(username, msg)
+	if err != nil || !ok || s.indexer == nil {
+		return err
+	}
+
+	// Индексируем уже без блокировки чата, чтобы перестроение индекса не
+	// задерживало запись следующих сообщений. Сообщение уже сохранено, индекс
+	// с пропуском перестроится при следующем поиске
+	if err := s.indexer.Index(username, ref, msg); err != nil {
+		logger.Warn("failed to index message", zap.String("chat_id", msg.ChatID), zap.Error(err))
+	}
+
+	return nil
+}
+
+// write дописывает сообщение в сегмент и возвращает его положение, ok ложно,
+// если сообщение оказалось копией уже сохранённого
+func (s *fileStore) write(username string, msg model.Message) (Ref, bool, error) {
+	chatDir, err := s.chatDir(username, msg.ChatID)
+	if err != nil {
+		return Ref{}, false, err
+	}
+
+	if err := os.MkdirAll(chatDir, dirPerm); err != nil {
+		return Ref{}, false, err
+	}
+
+	unlock, err := fileutil.Lock(chatDir, false)
+	if err != nil {
+		return Ref{}, false, err
+	}
+	defer unlock()
+
+	path := segmentPath(chatDir, msg.Time)
+
+	recent, err := readTail(path)
+	if err != nil {
+		return Ref{}, false, err
+	}
+	for _, m := range recent {
+		if isEcho(username, m, msg) {
+			return Ref{}, false, nil
+		}
+	}
+
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return Ref{}, false, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, fileutil.FilePerm)
+	if err != nil {
+		return Ref{}, false, err
+	}
+
+	// Под эксклюзивной блокировкой размер файла совпадает со смещением новой строки
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return Ref{}, false, err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return Ref{}, false, err
+	}
+
+	if err := f.Close(); err != nil {
+		return Ref{}, false, err
+	}
+
+	return Ref{
+		ChatID: msg.ChatID,
+		Day:    msg.Time.UTC().Format(segmentLayout),
+		Offset: info.Size(),
+	}, true, nil
+}
+
+func (s *fileStore) Query(username string, q Query) (Page, error) {
+	chatDir, err := s.chatDir(username, q.ChatID)
+	if err != nil {
+		return Page{}, err
+	}
+
+	days, err := segmentDays(chatDir)
+	if err != nil {
+		return Page{}, err
+	}
+	if len(days) == 0 {
+		return Page{}, nil
+	}
+
+	unlock, err := fileutil.Lock(chatDir, true)
+	if err != nil {
+		return Page{}, err
+	}
+	defer unlock()
+
+	// На одно сообщение больше, чтобы узнать, есть ли следующая страница
+	need := 0
+	if q.Limit > 0 {
+		need = q.Offset + q.Limit + 1
+	}
+
+	var newest []model.Message
+
+	for i := len(days) - 1; i >= 0; i-- {
+		day := days[i]
+		if !q.Until.IsZero() && day.After(q.Until) {
+			continue
+		}
+		if !q.Since.IsZero() && day.Add(24*time.Hour).Before(q.Since) {
+			break
+		}
+
+		segment, err := readSegment(segmentPath(chatDir, day))
+		if err != nil {
+			return Page{}, err
+		}
+
+		for j := len(segment) - 1; j >= 0; j-- {
+			if q.matches(segment[j]) {
+				newest = append(newest, segment[j])
+			}
+		}
+
+		if need > 0 && len(newest) >= need {
+			break
+		}
+	}
+
+	if q.Offset >= len(newest) {
+		return Page{}, nil
+	}
+	newest = newest[q.Offset:]
+
+	var page Page
+
+	if q.Limit > 0 && len(newest) > q.Limit {
+		newest = newest[:q.Limit]
+		page.More = true
+	}
+
+	page.Messages = make([]model.Message, len(newest))
+	for i, msg := range newest {
+		page.Messages[len(newest)-1-i] = msg
+	}
+
+	return page, nil
+}
+
+func (s *fileStore) Get(username string, refs []Ref) ([]model.Message, error) {
+	messages := make([]model.Message, 0, len(refs))
+
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for _, ref := range refs {
+		chatDir, err := s.chatDir(username, ref.ChatID)
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(chatDir, ref.Day+segmentExt)
+
+		f, ok := files[path]
+		if !ok {
+			f, err = os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			files[path] = f
+		}
+
+		msg, err := readLineAt(f, ref.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message at %s:%d: %w", filepath.Base(path), ref.Offset, err)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+func (s *fileStore) Around(username string, ref Ref, n int) ([]model.Message, int, error) {
+	chatDir, err := s.chatDir(username, ref.ChatID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		messages []model.Message
+		pos      = -1
+	)
+
+	err = scanSegment(filepath.Join(chatDir, ref.Day+segmentExt), func(offset int64, msg model.Message) error {
+		if offset == ref.Offset {
+			pos = len(messages)
+		}
+		messages = append(messages, msg)
+
+		if pos >= 0 && len(messages)-pos > n {
+			return errStopScan
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if pos < 0 {
+		return nil, 0, fmt.Errorf("message at %s:%d not found", ref.Day, ref.Offset)
+	}
+
+	start := pos - n
+	if start < 0 {
+		start = 0
+	}
+
+	return messages[start:], pos - start, nil
+}
+
+func (s *fileStore) Walk(username string, fn func(Ref, model.Message) error) error {
+	chatDirs, err := os.ReadDir(filepath.Join(s.dir, username))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range chatDirs {
+		if !entry.IsDir() {
+			continue
+		}
+
+		chatID := entry.Name()
+
+		chatDir, err := s.chatDir(username, chatID)
+		if err != nil {
+			continue
+		}
+
+		days, err := segmentDays(chatDir)
+		if err != nil {
+			return err
+		}
+
+		for _, day := range days {
+			dayName := day.Format(segmentLayout)
+
+			err := scanSegment(segmentPath(chatDir, day), func(offset int64, msg model.Message) error {
+				return fn(Ref{ChatID: chatID, Day: dayName, Offset: offset}, msg)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (q Query) matches(msg model.Message) bool {
+	if !q.Since.IsZero() && msg.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && msg.Time.After(q.Until) {
+		return false
+	}
+	if q.From != "" && msg.Username != q.From {
+		return false
+	}
+
+	return true
+}
+
+func (s *fileStore) chatDir(username, chatID string) (string, error) {
+	if _, err := strconv.ParseInt(chatID, 10, 64); err != nil {
+		return "", ErrInvalidChatID
+	}
+
+	return filepath.Join(s.dir, username, chatID), nil
+}
+
+func segmentPath(chatDir string, t time.Time) string {
+	return filepath.Join(chatDir, t.UTC().Format(segmentLayout)+segmentExt)
+}
+
+// segmentDays возвращает дни, за которые есть сегменты, по возрастанию
+func segmentDays(chatDir string) ([]time.Time, error) {
+	entries, err := os.ReadDir(chatDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]time.Time, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		day, err := time.Parse(segmentLayout, name)
+		if err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	return days, nil
+}
+
+func readSegment(path string) ([]model.Message, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return decodeLines(f)
+}
+
+// errStopScan прерывает scanSegment без ошибки
+var errStopScan = errors.New("stop scan")
+
+// scanSegment передаёт в fn сообщения сегмента вместе со смещениями их строк
+func scanSegment(path string, fn func(offset int64, msg model.Message) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			var msg model.Message
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr == nil {
+				if fnErr := fn(offset, msg); errors.Is(fnErr, errStopScan) {
+					return nil
+				} else if fnErr != nil {
+					return fnErr
+				}
+			}
+		}
+		offset += int64(len(line))
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readLineAt(f *os.File, offset int64) (model.Message, error) {
+	line, err := bufio.NewReader(io.NewSectionReader(f, offset, maxLineBytes)).ReadBytes('\n')
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	var msg model.Message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return model.Message{}, err
+	}
+
+	return msg, nil
+}
+
+// readTail читает последние сообщения сегмента
+func readTail(path string) ([]model.Message, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size() - tailSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	data := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	// Первая строка после смещения может быть обрезана
+	if offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	return decodeLines(bytes.NewReader(data))
+}
+
+// decodeLines разбирает JSON-строки, пропуская повреждённые
+func decodeLines(r io.Reader) ([]model.Message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+
+	var messages []model.Message
+	for scanner.Scan() {
+		var msg model.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, scanner.Err()
+}
+
+// isEcho сообщает, что msg вторая копия сохранённого сообщения аккаунта: одна
+// записывается при отправке, другая приходит из потока чата в любом порядке.
+// Повторы других участников и повторная отправка того же текста сохраняются
+func isEcho(username string, stored, msg model.Message) bool {
+	if msg.Username != username || stored.Username != username {
+		return false
+	}
+	if stored.Text != msg.Text || stored.Outgoing == msg.Outgoing {
+		return false
+	}
+
+	diff := stored.Time.Sub(msg.Time)
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff <= echoWindow
+}
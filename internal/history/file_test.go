@@ -0,0 +1,70 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+func appendAll(t *testing.T, store *fileStore, username string, messages ...model.Message) []model.Message {
+	t.Helper()
+
+	for _, msg := range messages {
+		if err := store.Append(username, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := store.Query(username, Query{ChatID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return page.Messages
+}
+
+func TestAppendKeepsRepeatedMessagesOfOthers(t *testing.T) {
+	store := NewFileStore(t.TempDir(), nil)
+	now := time.Now()
+
+	got := appendAll(t, store, "alice",
+		model.Message{ChatID: "1", Username: "bob", Text: "ok", Time: now},
+		model.Message{ChatID: "1", Username: "bob", Text: "ok", Time: now.Add(3 * time.Second)},
+	)
+
+	if len(got) != 2 {
+		t.Fatalf("stored %d messages, want both repeated messages: %+v", len(got), got)
+	}
+}
+
+func TestAppendDropsEchoOfSentMessage(t *testing.T) {
+	store := NewFileStore(t.TempDir(), nil)
+	now := time.Now()
+
+	got := appendAll(t, store, "alice",
+		model.Message{ChatID: "1", Username: "alice", Text: "ok", Time: now, Outgoing: true},
+		model.Message{ChatID: "1", Username: "alice", Text: "ok", Time: now.Add(time.Second)},
+	)
+
+	if len(got) != 1 || !got[0].Outgoing {
+		t.Fatalf("stored %+v, want only the sent copy", got)
+	}
+}
+
+func TestAppendKeepsRepeatedSentMessages(t *testing.T) {
+	store := NewFileStore(t.TempDir(), nil)
+	now := time.Now()
+
+	// Текст отправлен дважды, копия второй отправки пришла из потока раньше её записи
+	got := appendAll(t, store, "alice",
+		model.Message{ChatID: "1", Username: "alice", Text: "ok", Time: now, Outgoing: true},
+		model.Message{ChatID: "1", Username: "alice", Text: "ok", Time: now.Add(time.Second)},
+		model.Message{ChatID: "1", Username: "alice", Text: "ok", Time: now.Add(3 * time.Second)},
+		model.Message{ChatID: "1", Username: "alice", Text: "ok", Time: now.Add(3 * time.Second), Outgoing: true},
+	)
+
+	if len(got) != 2 {
+		t.Fatalf("stored %d messages, want one per send: %+v", len(got), got)
+	}
+}
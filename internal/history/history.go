@@ -0,0 +1,57 @@
+package history
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var ErrInvalidChatID = errors.New("chat id must be numeric")
+
+// Query отбор сообщений одного чата. Нулевые значения полей не ограничивают выборку
+type Query struct {
+	ChatID string
+	Since  time.Time
+	Until  time.Time
+	From   string
+	// Limit максимальное число сообщений на странице
+	Limit int
+	// Offset сколько самых новых подходящих сообщений пропустить
+	Offset int
+}
+
+// Page страница истории в хронологическом порядке
+type Page struct {
+	Messages []model.Message
+	// More есть ли более старые сообщения за пределами страницы
+	More bool
+}
+
+// Ref положение сохранённого сообщения в истории
+type Ref struct {
+	ChatID string `json:"chat_id"`
+	Day    string `json:"day"`
+	Offset int64  `json:"offset"`
+}
+
+// Indexer получает каждое новое сообщение сразу после сохранения. Ошибка
+// индексации не отменяет сохранение, индекс должен сам пометить себя неполным
+type Indexer interface {
+	Index(username string, ref Ref, msg model.Message) error
+}
+
+// Store локальная история сообщений пользователя
+type Store interface {
+	// Append сохраняет сообщения, пропуская уже сохранённые повторы
+	Append(username string, messages ...model.Message) error
+	// Query возвращает страницу сообщений, начиная с самых новых
+	Query(username string, q Query) (Page, error)
+	// Get читает сообщения по их положению в истории
+	Get(username string, refs []Ref) ([]model.Message, error)
+	// Around возвращает сообщение и до n соседних с каждой стороны,
+	// а также позицию самого сообщения в результате
+	Around(username string, ref Ref, n int) ([]model.Message, int, error)
+	// Walk обходит всю историю пользователя
+	Walk(username string, fn func(Ref, model.Message) error) error
+}
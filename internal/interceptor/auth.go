@@ -0,0 +1,149 @@
+package interceptor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mobo140/chat-cli/internal/token"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authHeader = "authorization"
+	authPrefix = "Bearer "
+)
+
+// AuthUnaryClientInterceptor добавляет access token к каждому вызову и
+// один раз повторяет вызов с новым токеном, если сервер его отклонил
+func AuthUnaryClientInterceptor(tokens token.Manager) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		accessToken, err := tokens.AccessToken(ctx)
+		if err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		err = invoker(withToken(ctx, accessToken), method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+
+		accessToken, err = refresh(ctx, tokens, method)
+		if err != nil {
+			return err
+		}
+
+		return invoker(withToken(ctx, accessToken), method, req, reply, cc, opts...)
+	}
+}
+
+// AuthStreamClientInterceptor то же для стримов. Для серверных стримов
+// ошибка авторизации приходит при первом чтении, поэтому стрим
+// переоткрывается с тем же запросом, если ещё не было получено ни одного сообщения
+func AuthStreamClientInterceptor(tokens token.Manager) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		accessToken, err := tokens.AccessToken(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		stream, err := streamer(withToken(ctx, accessToken), desc, cc, method, opts...)
+		if status.Code(err) == codes.Unauthenticated {
+			accessToken, err = refresh(ctx, tokens, method)
+			if err != nil {
+				return nil, err
+			}
+
+			return streamer(withToken(ctx, accessToken), desc, cc, method, opts...)
+		}
+		if err != nil || desc.ClientStreams || !desc.ServerStreams {
+			return stream, err
+		}
+
+		return &retryStream{
+			ClientStream: stream,
+			reopen: func() (grpc.ClientStream, error) {
+				accessToken, err := refresh(ctx, tokens, method)
+				if err != nil {
+					return nil, err
+				}
+
+				return streamer(withToken(ctx, accessToken), desc, cc, method, opts...)
+			},
+		}, nil
+	}
+}
+
+type retryStream struct {
+	grpc.ClientStream
+
+	mu       sync.Mutex
+	reopen   func() (grpc.ClientStream, error)
+	request  interface{}
+	received bool
+}
+
+func (s *retryStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	s.request = m
+	s.mu.Unlock()
+
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *retryStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		s.received = true
+		return nil
+	}
+	if s.received || s.reopen == nil || s.request == nil || status.Code(err) != codes.Unauthenticated {
+		return err
+	}
+
+	reopen := s.reopen
+	s.reopen = nil
+
+	stream, reopenErr := reopen()
+	if reopenErr != nil {
+		return reopenErr
+	}
+	if err := stream.SendMsg(s.request); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	s.ClientStream = stream
+
+	err = stream.RecvMsg(m)
+	if err == nil {
+		s.received = true
+	}
+
+	return err
+}
+
+func refresh(ctx context.Context, tokens token.Manager, method string) (string, error) {
+	logger.Debug("Access token rejected, refreshing", zap.String("method", method))
+
+	accessToken, err := tokens.RefreshAccessToken(ctx)
+	if err != nil {
+		logger.Error("failed to refresh access token", zap.Error(err))
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return accessToken, nil
+}
+
+func withToken(ctx context.Context, accessToken string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authHeader, authPrefix+accessToken)
+}
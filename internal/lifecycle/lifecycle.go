@@ -0,0 +1,148 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Component часть приложения, которая запускается при старте и
+// останавливается при выходе. Любое из полей кроме Name может быть nil
+type Component struct {
+	Name string
+	// Run работает в отдельной горутине до отмены контекста
+	Run func(ctx context.Context) error
+	// Stop вызывается после завершения Run
+	Stop func(ctx context.Context) error
+}
+
+// Lifecycle запускает компоненты в порядке добавления и останавливает
+// в обратном, так что каждый компонент переживает те, что от него зависят
+type Lifecycle struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+
+	mu      sync.Mutex
+	running []*running
+	stopped bool
+}
+
+type running struct {
+	component Component
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// New создаёт корневой контекст приложения, timeout ограничивает время остановки
+// каждого компонента, чтобы зависший компонент не мешал остановить остальные
+func New(ctx context.Context, timeout time.Duration) *Lifecycle {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Lifecycle{
+		ctx:     ctx,
+		cancel:  cancel,
+		timeout: timeout,
+	}
+}
+
+// Context корневой контекст, отменяется при остановке или ошибке компонента
+func (l *Lifecycle) Context() context.Context {
+	return l.ctx
+}
+
+// Start запускает компонент. Ошибка Run считается фатальной и отменяет корневой контекст
+func (l *Lifecycle) Start(c Component) {
+	ctx, cancel := context.WithCancel(l.ctx)
+	r := &running{component: c, cancel: cancel, done: make(chan struct{})}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopped {
+		cancel()
+		close(r.done)
+		return
+	}
+	l.running = append(l.running, r)
+
+	if c.Run == nil {
+		close(r.done)
+		return
+	}
+
+	go func() {
+		defer close(r.done)
+
+		if err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("component failed, shutting down", zap.String("component", c.Name), zap.Error(err))
+			l.cancel()
+		}
+	}()
+}
+
+// Shutdown останавливает компоненты в обратном порядке. Компонент, не успевший
+// остановиться за отведённое время, пропускается
+func (l *Lifecycle) Shutdown() error {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return nil
+	}
+	l.stopped = true
+	components := l.running
+	l.running = nil
+	l.mu.Unlock()
+
+	defer l.cancel()
+
+	var errs []error
+
+	for i := len(components) - 1; i >= 0; i-- {
+		if err := components[i].stop(l.timeout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *running) stop(timeout time.Duration) error {
+	name := r.component.Name
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	r.cancel()
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return fmt.Errorf("%s: did not stop in time", name)
+	}
+
+	if r.component.Stop != nil {
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- r.component.Stop(ctx)
+		}()
+
+		select {
+		case err := <-stopped:
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("%s: did not stop in time", name)
+		}
+	}
+
+	logger.Debug("component stopped", zap.String("component", name))
+
+	return nil
+}
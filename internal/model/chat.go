@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type Chat struct {
+	ID           string    `json:"id"`
+	Alias        string    `json:"alias,omitempty"`
+	Members      []string  `json:"members,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	LastActivity time.Time `json:"last_activity"`
+}
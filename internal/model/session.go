@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+type Session struct {
+	RefreshToken string    `json:"refresh_token"`
+	AccessToken  string    `json:"access_token"`
+	Username     string    `json:"username"`
+	RefreshedAt  time.Time `json:"refreshed_at,omitempty"`
+	Messages     []Message `json:"messages"`
+}
+
+type Message struct {
+	ChatID   string    `json:"chat_id"`
+	Username string    `json:"username"`
+	Text     string    `json:"text"`
+	Time     time.Time `json:"time"`
+	// Outgoing сообщение сохранено этим клиентом при отправке, а не получено из потока чата
+	Outgoing bool `json:"outgoing,omitempty"`
+}
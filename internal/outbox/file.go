@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/gofrs/flock"
+)
+
+const (
+	fileExt       = ".json"
+	flusherSuffix = ".flusher"
+	dirPerm       = 0o700
+)
+
+var (
+	_ Store   = (*fileStore)(nil)
+	_ Elector = (*fileStore)(nil)
+)
+
+// fileStore хранит очередь каждого аккаунта в файле <dir>/<account>.json
+type fileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *fileStore {
+	return &fileStore{dir: dir}
+}
+
+func (s *fileStore) Add(account string, item Item) error {
+	return s.update(account, func(items []Item) ([]Item, error) {
+		return append(items, item), nil
+	})
+}
+
+func (s *fileStore) List(account string) ([]Item, error) {
+	path := s.path(account)
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	unlock, err := fileutil.Lock(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return s.read(path)
+}
+
+func (s *fileStore) Update(account, id string, fn func(*Item)) error {
+	return s.update(account, func(items []Item) ([]Item, error) {
+		for i := range items {
+			if items[i].ID == id {
+				fn(&items[i])
+				return items, nil
+			}
+		}
+
+		return nil, ErrNotFound
+	})
+}
+
+func (s *fileStore) Remove(account string, ids ...string) error {
+	return s.update(account, func(items []Item) ([]Item, error) {
+		remove := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			remove[id] = true
+		}
+
+		kept := items[:0]
+		for _, item := range items {
+			if remove[item.ID] {
+				delete(remove, item.ID)
+				continue
+			}
+			kept = append(kept, item)
+		}
+
+		for id := range remove {
+			return nil, fmt.Errorf("%s: %w", id, ErrNotFound)
+		}
+
+		return kept, nil
+	})
+}
+
+func (s *fileStore) Accounts() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.Contains(name, fileutil.TempMarker) {
+			continue
+		}
+
+		if account, ok := strings.CutSuffix(name, fileExt); ok {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return accounts, nil
+}
+
+// TryAcquireFlusher захватывает отправку очереди аккаунта. Блокировка снимается
+// при вызове release или завершении процесса
+func (s *fileStore) TryAcquireFlusher(account string) (func(), bool, error) {
+	if err := os.MkdirAll(s.dir, dirPerm); err != nil {
+		return nil, false, err
+	}
+
+	lock := flock.New(s.path(account)+flusherSuffix, flock.SetPermissions(fileutil.FilePerm))
+
+	ok, err := lock.TryLock()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	return func() { lock.Unlock() }, true, nil
+}
+
+func (s *fileStore) path(account string) string {
+	return filepath.Join(s.dir, account+fileExt)
+}
+
+func (s *fileStore) read(path string) ([]Item, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// update изменяет очередь под эксклюзивной блокировкой. Пустая очередь удаляется
+func (s *fileStore) update(account string, fn func(items []Item) ([]Item, error)) error {
+	if err := os.MkdirAll(s.dir, dirPerm); err != nil {
+		return err
+	}
+
+	path := s.path(account)
+
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	items, err := s.read(path)
+	if err != nil {
+		return err
+	}
+
+	items, err = fn(items)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(path, data)
+}
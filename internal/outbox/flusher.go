@@ -0,0 +1,204 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/backoff"
+	"github.com/Mobo140/chat-cli/internal/clients"
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	sendTimeout   = 20 * time.Second
+	pollInterval  = 30 * time.Second
+	minRetryDelay = 2 * time.Second
+	maxRetryDelay = time.Minute
+)
+
+// errRetryLater отправка отложена до следующей попытки
+var errRetryLater = errors.New("outbox delivery postponed")
+
+// Flusher отправляет сообщения из очереди в фоне
+type Flusher struct {
+	store  Store
+	client clients.ChatServiceClient
+	onSent func(account string, item Item)
+	notify chan struct{}
+}
+
+// NewFlusher создаёт отправщик очереди. onSent вызывается для каждого доставленного сообщения
+func NewFlusher(store Store, client clients.ChatServiceClient, onSent func(account string, item Item)) *Flusher {
+	return &Flusher{
+		store:  store,
+		client: client,
+		onSent: onSent,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Notify просит отправить очередь, не дожидаясь следующего опроса
+func (f *Flusher) Notify() {
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (f *Flusher) Run(ctx context.Context) {
+	var (
+		retry      = backoff.New(minRetryDelay, maxRetryDelay)
+		wait       time.Duration
+		backingOff bool
+	)
+
+	for {
+		// Пока сервер недоступен, Notify не ускоряет следующую попытку
+		var notify <-chan struct{}
+		if !backingOff {
+			notify = f.notify
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		case <-notify:
+			timer.Stop()
+		}
+
+		err := f.flush(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		backingOff = errors.Is(err, errRetryLater)
+		if backingOff {
+			wait = retry.Next()
+			logger.Debug("Outbox delivery postponed", zap.Duration("retry_in", wait))
+		} else {
+			retry.Reset()
+			wait = pollInterval
+		}
+	}
+}
+
+func (f *Flusher) flush(ctx context.Context) error {
+	accounts, err := f.store.Accounts()
+	if err != nil {
+		logger.Error("failed to read outbox", zap.Error(err))
+		return nil
+	}
+
+	var result error
+	for _, acc := range accounts {
+		if err := f.flushAccount(ctx, acc); err != nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+func (f *Flusher) flushAccount(ctx context.Context, acc string) error {
+	if elector, ok := f.store.(Elector); ok {
+		release, ok, err := elector.TryAcquireFlusher(acc)
+		if err != nil {
+			logger.Error("failed to lock outbox", zap.String("account", acc), zap.Error(err))
+			return nil
+		}
+		if !ok {
+			// Очередь отправляет другой процесс
+			return nil
+		}
+		defer release()
+	}
+
+	items, err := f.store.List(acc)
+	if err != nil {
+		logger.Error("failed to read outbox", zap.String("account", acc), zap.Error(err))
+		return nil
+	}
+
+	for _, item := range items {
+		if item.Status != StatusPending {
+			continue
+		}
+
+		err := f.send(ctx, acc, item)
+		switch {
+		case err == nil:
+			if err := f.store.Remove(acc, item.ID); err != nil && !errors.Is(err, ErrNotFound) {
+				logger.Error("failed to remove delivered message from outbox", zap.String("id", item.ID), zap.Error(err))
+			}
+
+			logger.Info("Queued message delivered", zap.String("id", item.ID), zap.String("chat_id", item.ChatID))
+
+			if f.onSent != nil {
+				f.onSent(acc, item)
+			}
+		case MaybeDelivered(err):
+			f.markAttempt(acc, item.ID, StatusFailed, err)
+
+			logger.Warn("Queued message may have been delivered, resend it with 'outbox retry' if it did not arrive",
+				zap.String("id", item.ID),
+				zap.String("chat_id", item.ChatID),
+				zap.Error(err))
+		case retryLater(err):
+			f.markAttempt(acc, item.ID, StatusPending, err)
+
+			// Остальные сообщения ждут, чтобы сохранить порядок отправки
+			return errRetryLater
+		default:
+			f.markAttempt(acc, item.ID, StatusFailed, err)
+
+			logger.Warn("Queued message rejected by server, drop it with 'outbox drop'",
+				zap.String("id", item.ID),
+				zap.String("chat_id", item.ChatID),
+				zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (f *Flusher) send(ctx context.Context, acc string, item Item) error {
+	ctx, cancel := context.WithTimeout(account.WithUsername(ctx, acc), sendTimeout)
+	defer cancel()
+
+	return f.client.SendMessage(ctx, &chat.Message{
+		ChatID:   item.ChatID,
+		Text:     item.Text,
+		Username: item.Username,
+	})
+}
+
+func (f *Flusher) markAttempt(acc, id string, st Status, sendErr error) {
+	err := f.store.Update(acc, id, func(item *Item) {
+		item.Attempts++
+		item.Status = st
+		item.LastError = sendErr.Error()
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		logger.Error("failed to update outbox", zap.String("id", id), zap.Error(err))
+	}
+}
+
+// retryLater сообщает, что сообщение стоит отправить позже: сервер недоступен
+// или у аккаунта сейчас нет действующей сессии
+func retryLater(err error) bool {
+	if IsRetryable(err) {
+		return true
+	}
+
+	st, ok := status.FromError(err)
+	return !ok || st.Code() == codes.Unauthenticated
+}
@@ -0,0 +1,187 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClient отправляет сообщения успешно, пока для текста не задана ошибка.
+// Ошибки из errs возвращаются по одной на попытку
+type fakeClient struct {
+	mu   sync.Mutex
+	sent []string
+	errs map[string][]error
+}
+
+func (c *fakeClient) Create(context.Context, []string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (c *fakeClient) Delete(context.Context, string) error {
+	return errors.New("not implemented")
+}
+
+func (c *fakeClient) SendMessage(_ context.Context, msg *chat.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if errs := c.errs[msg.Text]; len(errs) > 0 {
+		c.errs[msg.Text] = errs[1:]
+		return errs[0]
+	}
+	c.sent = append(c.sent, msg.Text)
+
+	return nil
+}
+
+func (c *fakeClient) ConnectChat(ctx context.Context, _, _ string, _ func(*chat.Message)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type flusherTest struct {
+	store   *fileStore
+	client  *fakeClient
+	flusher *Flusher
+	// delivered тексты, переданные в onSent
+	delivered []string
+}
+
+func newFlusherTest(t *testing.T, errs map[string][]error, texts ...string) *flusherTest {
+	t.Helper()
+
+	logger.Init(zapcore.NewNopCore())
+
+	ft := &flusherTest{
+		store:  NewFileStore(t.TempDir()),
+		client: &fakeClient{errs: errs},
+	}
+	ft.flusher = NewFlusher(ft.store, ft.client, func(_ string, item Item) {
+		ft.delivered = append(ft.delivered, item.Text)
+	})
+
+	for _, text := range texts {
+		if err := ft.store.Add("bob", NewItem("1", "bob", text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return ft
+}
+
+// items возвращает оставшиеся в очереди сообщения по тексту
+func (ft *flusherTest) items(t *testing.T) map[string]Item {
+	t.Helper()
+
+	list, err := ft.store.List("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := make(map[string]Item, len(list))
+	for _, item := range list {
+		items[item.Text] = item
+	}
+
+	return items
+}
+
+func TestFlushSendsInOrderAndRemovesDelivered(t *testing.T) {
+	ft := newFlusherTest(t, nil, "first", "second", "third")
+
+	if err := ft.flusher.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"first", "second", "third"}
+	if !reflect.DeepEqual(ft.client.sent, want) {
+		t.Fatalf("sent %q, want %q", ft.client.sent, want)
+	}
+	if !reflect.DeepEqual(ft.delivered, want) {
+		t.Fatalf("onSent got %q, want %q", ft.delivered, want)
+	}
+	if items := ft.items(t); len(items) != 0 {
+		t.Fatalf("delivered messages left in outbox: %+v", items)
+	}
+}
+
+func TestFlushWaitsForUnavailableServerInOrder(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	ft := newFlusherTest(t, map[string][]error{"first": {unavailable}}, "first", "second")
+
+	if err := ft.flusher.flush(context.Background()); !errors.Is(err, errRetryLater) {
+		t.Fatalf("flush error = %v, want errRetryLater", err)
+	}
+	if len(ft.client.sent) != 0 {
+		t.Fatalf("sent %q while the first message is waiting", ft.client.sent)
+	}
+
+	items := ft.items(t)
+	if first := items["first"]; first.Status != StatusPending || first.Attempts != 1 || first.LastError == "" {
+		t.Fatalf("first = %+v, want pending after one attempt", first)
+	}
+	if second := items["second"]; second.Status != StatusPending || second.Attempts != 0 {
+		t.Fatalf("second = %+v, want untouched", second)
+	}
+
+	if err := ft.flusher.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(ft.client.sent, want) {
+		t.Fatalf("sent %q, want %q", ft.client.sent, want)
+	}
+	if items := ft.items(t); len(items) != 0 {
+		t.Fatalf("delivered messages left in outbox: %+v", items)
+	}
+}
+
+func TestFlushRetriesUnauthenticatedLater(t *testing.T) {
+	unauthenticated := status.Error(codes.Unauthenticated, "token expired")
+	ft := newFlusherTest(t, map[string][]error{"first": {unauthenticated}}, "first", "second")
+
+	if err := ft.flusher.flush(context.Background()); !errors.Is(err, errRetryLater) {
+		t.Fatalf("flush error = %v, want errRetryLater", err)
+	}
+	if first := ft.items(t)["first"]; first.Status != StatusPending {
+		t.Fatalf("first = %+v, want pending until the session is refreshed", first)
+	}
+	if len(ft.client.sent) != 0 {
+		t.Fatalf("sent %q while the first message is waiting", ft.client.sent)
+	}
+}
+
+func TestFlushKeepsRejectedAndMaybeDeliveredMessages(t *testing.T) {
+	ft := newFlusherTest(t, map[string][]error{
+		"rejected":  {status.Error(codes.InvalidArgument, "message is too long")},
+		"timed out": {status.Error(codes.DeadlineExceeded, "deadline exceeded")},
+	}, "rejected", "timed out", "delivered")
+
+	for i := 0; i < 2; i++ {
+		if err := ft.flusher.flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"delivered"}; !reflect.DeepEqual(ft.client.sent, want) {
+		t.Fatalf("sent %q, want %q: failed messages must not be resent", ft.client.sent, want)
+	}
+
+	items := ft.items(t)
+	if len(items) != 2 {
+		t.Fatalf("outbox = %+v, want only the failed messages", items)
+	}
+	for _, text := range []string{"rejected", "timed out"} {
+		if item := items[text]; item.Status != StatusFailed || item.Attempts != 1 {
+			t.Errorf("%s = %+v, want failed after one attempt", text, item)
+		}
+	}
+}
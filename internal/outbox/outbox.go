@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrNotFound = errors.New("message not found in outbox")
+
+type Status string
+
+const (
+	// StatusPending сообщение ждёт повторной отправки
+	StatusPending Status = "pending"
+	// StatusFailed сервер отклонил сообщение или мог уже принять его, автоматически
+	// оно больше не отправляется
+	StatusFailed Status = "failed"
+)
+
+// Item сообщение, ожидающее отправки
+type Item struct {
+	ID        string    `json:"id"`
+	ChatID    string    `json:"chat_id"`
+	Username  string    `json:"username"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int       `json:"attempts"`
+	Status    Status    `json:"status"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Store очередь неотправленных сообщений каждого аккаунта в порядке добавления
+type Store interface {
+	Add(account string, item Item) error
+	List(account string) ([]Item, error)
+	Update(account, id string, fn func(*Item)) error
+	// Remove удаляет сообщения и возвращает ErrNotFound, если какого-то из них нет
+	Remove(account string, ids ...string) error
+	// Accounts возвращает аккаунты, у которых есть сообщения в очереди
+	Accounts() ([]string, error)
+}
+
+// Elector выбирает один процесс, отправляющий очередь аккаунта
+type Elector interface {
+	TryAcquireFlusher(account string) (release func(), ok bool, err error)
+}
+
+// NewItem создаёт сообщение очереди с идентификатором, сгенерированным на клиенте
+func NewItem(chatID, username, text string) Item {
+	return Item{
+		ID:        newID(),
+		ChatID:    chatID,
+		Username:  username,
+		Text:      text,
+		CreatedAt: time.Now(),
+		Status:    StatusPending,
+	}
+}
+
+// IsRetryable сообщает, что сервер недоступен и сообщение точно не принято,
+// поэтому его можно поставить в очередь и отправить повторно
+func IsRetryable(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// MaybeDelivered сообщает, что ответ сервера не дождались и сообщение могло быть
+// принято. Сервер не различает повторные отправки, поэтому автоматический повтор
+// может продублировать сообщение
+func MaybeDelivered(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Canceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pending считает сообщения, которые ещё будут отправлены
+func Pending(items []Item) int {
+	n := 0
+	for _, item := range items {
+		if item.Status == StatusPending {
+			n++
+		}
+	}
+
+	return n
+}
+
+func newID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("150405.000000")
+	}
+
+	return hex.EncodeToString(b)
+}
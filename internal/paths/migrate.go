@@ -0,0 +1,85 @@
+package paths
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	legacySessionPrefix = ".chat-cli-session."
+	legacyAccountFile   = ".chat-cli-account"
+	legacyHistoryFile   = ".chat-cli-history"
+	legacyLockSuffix    = ".lock"
+)
+
+// Migration результат переноса одного файла
+type Migration struct {
+	From string
+	To   string
+	Err  error
+}
+
+// MigrateLegacy переносит файлы, которые раньше создавались в текущем
+// каталоге, в каталог состояния. Существующие файлы не перезаписываются,
+// устаревшие lock-файлы удаляются
+func (d *Dirs) MigrateLegacy(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+
+		var target string
+		switch {
+		case strings.HasPrefix(name, legacySessionPrefix) && strings.HasSuffix(name, legacyLockSuffix):
+			os.Remove(filepath.Join(dir, name))
+			continue
+		case strings.HasPrefix(name, legacySessionPrefix):
+			target = d.SessionFile() + "." + strings.TrimPrefix(name, legacySessionPrefix)
+		case name == legacyAccountFile:
+			target = d.AccountFile()
+		case name == legacyHistoryFile:
+			target = d.HistoryFile()
+		default:
+			continue
+		}
+
+		from := filepath.Join(dir, name)
+		if from == target {
+			continue
+		}
+
+		migrations = append(migrations, Migration{From: from, To: target, Err: moveFile(from, target)})
+	}
+
+	return migrations, nil
+}
+
+func moveFile(from, to string) error {
+	if _, err := os.Stat(to); err == nil {
+		return errors.New("target already exists")
+	}
+
+	if err := os.Rename(from, to); err == nil {
+		return os.Chmod(to, 0o600)
+	}
+
+	// Каталоги могут быть на разных файловых системах
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(to, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Remove(from)
+}
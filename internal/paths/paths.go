@@ -0,0 +1,116 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	appName = "chat-cli"
+	dirPerm = 0o700
+
+	xdgConfigHomeEnv = "XDG_CONFIG_HOME"
+	xdgStateHomeEnv  = "XDG_STATE_HOME"
+)
+
+// Dirs каталоги конфигурации и состояния приложения по спецификации XDG
+type Dirs struct {
+	config string
+	state  string
+}
+
+// New определяет каталоги приложения. stateDir переопределяет оба каталога,
+// иначе используются XDG_CONFIG_HOME и XDG_STATE_HOME или их значения по умолчанию
+func New(stateDir string) (*Dirs, error) {
+	if stateDir != "" {
+		abs, err := filepath.Abs(stateDir)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Dirs{config: abs, state: abs}, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return &Dirs{
+		config: filepath.Join(xdgDir(xdgConfigHomeEnv, filepath.Join(home, ".config")), appName),
+		state:  filepath.Join(xdgDir(xdgStateHomeEnv, filepath.Join(home, ".local", "state")), appName),
+	}, nil
+}
+
+// Ensure создаёт каталоги, доступные только владельцу
+func (d *Dirs) Ensure() error {
+	for _, dir := range []string{d.config, d.state, d.SessionDir(), d.ChatsDir(), d.MessagesDir(), d.IndexDir(), d.OutboxDir(), d.LogDir()} {
+		if err := os.MkdirAll(dir, dirPerm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Dirs) ConfigDir() string {
+	return d.config
+}
+
+func (d *Dirs) StateDir() string {
+	return d.state
+}
+
+func (d *Dirs) SessionDir() string {
+	return filepath.Join(d.state, "sessions")
+}
+
+// SessionFile базовый путь файлов сессий, к нему добавляется .<username>
+func (d *Dirs) SessionFile() string {
+	return filepath.Join(d.SessionDir(), "session")
+}
+
+func (d *Dirs) ChatsDir() string {
+	return filepath.Join(d.state, "chats")
+}
+
+func (d *Dirs) MessagesDir() string {
+	return filepath.Join(d.state, "messages")
+}
+
+func (d *Dirs) IndexDir() string {
+	return filepath.Join(d.state, "index")
+}
+
+func (d *Dirs) OutboxDir() string {
+	return filepath.Join(d.state, "outbox")
+}
+
+func (d *Dirs) AccountFile() string {
+	return filepath.Join(d.state, "account")
+}
+
+func (d *Dirs) HistoryFile() string {
+	return filepath.Join(d.state, "history")
+}
+
+func (d *Dirs) LogDir() string {
+	return filepath.Join(d.state, "logs")
+}
+
+func (d *Dirs) LogFile() string {
+	return filepath.Join(d.LogDir(), "app.log")
+}
+
+func (d *Dirs) CredentialsFile() string {
+	return filepath.Join(d.config, "credentials.json")
+}
+
+func xdgDir(env string, def string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return def
+}
@@ -0,0 +1,242 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var _ Registry = (*fileRegistry)(nil)
+
+// fileRegistry хранит чаты каждого пользователя в отдельном JSON-файле
+type fileRegistry struct {
+	dir string
+}
+
+func NewFileRegistry(dir string) *fileRegistry {
+	return &fileRegistry{dir: dir}
+}
+
+func (r *fileRegistry) Touch(username string, chat model.Chat) error {
+	return r.update(username, func(chats map[string]*model.Chat) error {
+		existing, ok := chats[chat.ID]
+		if !ok {
+			c := chat
+			// Время создания записи, а не последнего сообщения чата
+			c.CreatedAt = time.Now()
+			chats[chat.ID] = &c
+			return nil
+		}
+
+		existing.Members = mergeMembers(existing.Members, chat.Members)
+		if chat.LastActivity.After(existing.LastActivity) {
+			existing.LastActivity = chat.LastActivity
+		}
+
+		return nil
+	})
+}
+
+func (r *fileRegistry) List(username string) ([]model.Chat, error) {
+	chats, err := r.load(username, true)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]model.Chat, 0, len(chats))
+	for _, chat := range chats {
+		list = append(list, *chat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastActivity.After(list[j].LastActivity)
+	})
+
+	return list, nil
+}
+
+func (r *fileRegistry) SetAlias(username, chatRef, alias string) error {
+	if alias == "" || isNumeric(alias) {
+		return ErrInvalidAlias
+	}
+
+	return r.update(username, func(chats map[string]*model.Chat) error {
+		id, err := resolve(chats, chatRef)
+		if err != nil {
+			return err
+		}
+
+		for _, chat := range chats {
+			if chat.Alias == alias && chat.ID != id {
+				return ErrAliasTaken
+			}
+		}
+
+		chat, ok := chats[id]
+		if !ok {
+			chat = &model.Chat{ID: id, CreatedAt: time.Now()}
+			chats[id] = chat
+		}
+		chat.Alias = alias
+
+		return nil
+	})
+}
+
+func (r *fileRegistry) Forget(username, chatRef string) error {
+	return r.update(username, func(chats map[string]*model.Chat) error {
+		id, err := resolve(chats, chatRef)
+		if err != nil {
+			return err
+		}
+		if _, ok := chats[id]; !ok {
+			return ErrNotFound
+		}
+		delete(chats, id)
+
+		return nil
+	})
+}
+
+func (r *fileRegistry) Resolve(username, chatRef string) (string, error) {
+	if isNumeric(chatRef) {
+		return chatRef, nil
+	}
+
+	chats, err := r.load(username, true)
+	if err != nil {
+		return "", err
+	}
+
+	return resolve(chats, chatRef)
+}
+
+// path проверяет имя пользователя, чтобы файл реестра не оказался вне каталога
+func (r *fileRegistry) path(username string) (string, error) {
+	if err := fileutil.ValidName(username); err != nil {
+		return "", fmt.Errorf("invalid username: %w", err)
+	}
+
+	return filepath.Join(r.dir, username+".json"), nil
+}
+
+func (r *fileRegistry) load(username string, lock bool) (map[string]*model.Chat, error) {
+	path, err := r.path(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if lock {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return map[string]*model.Chat{}, nil
+		}
+
+		unlock, err := fileutil.Lock(path, true)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]*model.Chat{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*model.Chat
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	chats := make(map[string]*model.Chat, len(list))
+	for _, chat := range list {
+		chats[chat.ID] = chat
+	}
+
+	return chats, nil
+}
+
+func (r *fileRegistry) update(username string, fn func(chats map[string]*model.Chat) error) error {
+	path, err := r.path(username)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return err
+	}
+
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	chats, err := r.load(username, false)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(chats); err != nil {
+		return err
+	}
+
+	list := make([]*model.Chat, 0, len(chats))
+	for _, chat := range chats {
+		list = append(list, chat)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(path, data)
+}
+
+func resolve(chats map[string]*model.Chat, chatRef string) (string, error) {
+	if isNumeric(chatRef) {
+		return chatRef, nil
+	}
+
+	for _, chat := range chats {
+		if chat.Alias == chatRef {
+			return chat.ID, nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+func mergeMembers(members []string, more []string) []string {
+	seen := make(map[string]struct{}, len(members))
+	for _, m := range members {
+		seen[m] = struct{}{}
+	}
+
+	for _, m := range more {
+		if _, ok := seen[m]; ok || m == "" {
+			continue
+		}
+		seen[m] = struct{}{}
+		members = append(members, m)
+	}
+
+	return members
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
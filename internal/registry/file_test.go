@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+func TestSetAliasOfUnknownChatSetsCreatedAt(t *testing.T) {
+	r := NewFileRegistry(t.TempDir())
+
+	before := time.Now()
+	if err := r.SetAlias("bob", "42", "team"); err != nil {
+		t.Fatal(err)
+	}
+
+	chats, err := r.List("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chats) != 1 || chats[0].ID != "42" || chats[0].Alias != "team" {
+		t.Fatalf("chats = %+v, want chat 42 aliased team", chats)
+	}
+	if chats[0].CreatedAt.Before(before) {
+		t.Fatalf("CreatedAt = %v, want the time the alias was set", chats[0].CreatedAt)
+	}
+}
+
+func TestFileRegistryRejectsUsernamesOutsideDir(t *testing.T) {
+	r := NewFileRegistry(filepath.Join(t.TempDir(), "chats"))
+
+	for _, username := range []string{"", ".", "..", "x/../../y", "a/b", `a\b`} {
+		if err := r.Touch(username, model.Chat{ID: "1"}); !errors.Is(err, fileutil.ErrInvalidName) {
+			t.Errorf("Touch(%q) error = %v, want ErrInvalidName", username, err)
+		}
+		if _, err := r.List(username); !errors.Is(err, fileutil.ErrInvalidName) {
+			t.Errorf("List(%q) error = %v, want ErrInvalidName", username, err)
+		}
+	}
+}
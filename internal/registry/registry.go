@@ -0,0 +1,25 @@
+package registry
+
+import (
+	"errors"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var (
+	ErrNotFound     = errors.New("chat not found in local registry")
+	ErrAliasTaken   = errors.New("alias is already used by another chat")
+	ErrInvalidAlias = errors.New("alias must not be empty or numeric")
+)
+
+// Registry локальный реестр чатов пользователя: ID, участники, псевдонимы
+type Registry interface {
+	// Touch добавляет чат или обновляет участников и время последней активности.
+	// CreatedAt проставляется только при добавлении чата
+	Touch(username string, chat model.Chat) error
+	List(username string) ([]model.Chat, error)
+	SetAlias(username, chatRef, alias string) error
+	Forget(username, chatRef string) error
+	// Resolve возвращает ID чата по числовому ID или псевдониму
+	Resolve(username, chatRef string) (string, error)
+}
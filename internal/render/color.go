@@ -0,0 +1,46 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var _ Renderer = (*colorRenderer)(nil)
+
+// colorRenderer текст со временем, чат и автор выделены своими цветами
+type colorRenderer struct {
+	color bool
+}
+
+func (r *colorRenderer) Message(w io.Writer, chat string, msg model.Message) error {
+	stamp := r.paint(colorDim, msg.Time.Local().Format(time.TimeOnly))
+	label := r.paint(colorOf(chat), "["+chat+"]")
+	user := r.paint(colorOf(msg.Username), msg.Username)
+
+	if action, ok := strings.CutPrefix(msg.Text, ActionPrefix); ok {
+		_, err := fmt.Fprintf(w, "%s %s * %s %s\n", stamp, label, user, action)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%s %s %s: %s\n", stamp, label, user, msg.Text)
+	return err
+}
+
+func (r *colorRenderer) Status(w io.Writer, chat string, text string) error {
+	line := fmt.Sprintf("%s *** [%s] %s", time.Now().Format(time.TimeOnly), chat, text)
+
+	_, err := fmt.Fprintln(w, r.paint(colorDim, line))
+	return err
+}
+
+func (r *colorRenderer) paint(color, s string) string {
+	if !r.color {
+		return s
+	}
+
+	return color + s + colorReset
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+const (
+	typeMessage = "message"
+	typeStatus  = "status"
+)
+
+var _ Renderer = (*jsonRenderer)(nil)
+
+// jsonRenderer по одному JSON-объекту на строку для обработки другими программами
+type jsonRenderer struct{}
+
+type jsonLine struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Chat     string    `json:"chat"`
+	ChatID   string    `json:"chat_id,omitempty"`
+	Username string    `json:"username,omitempty"`
+	Text     string    `json:"text"`
+}
+
+func (r *jsonRenderer) Message(w io.Writer, chat string, msg model.Message) error {
+	return r.write(w, jsonLine{
+		Type:     typeMessage,
+		Time:     msg.Time,
+		Chat:     chat,
+		ChatID:   msg.ChatID,
+		Username: msg.Username,
+		Text:     msg.Text,
+	})
+}
+
+func (r *jsonRenderer) Status(w io.Writer, chat string, text string) error {
+	return r.write(w, jsonLine{
+		Type: typeStatus,
+		Time: time.Now(),
+		Chat: chat,
+		Text: text,
+	})
+}
+
+func (r *jsonRenderer) write(w io.Writer, line jsonLine) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(line)
+}
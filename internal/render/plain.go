@@ -0,0 +1,29 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var _ Renderer = (*plainRenderer)(nil)
+
+// plainRenderer текст без времени и цветов: [chat] user: text
+type plainRenderer struct{}
+
+func (r *plainRenderer) Message(w io.Writer, chat string, msg model.Message) error {
+	if action, ok := strings.CutPrefix(msg.Text, ActionPrefix); ok {
+		_, err := fmt.Fprintf(w, "[%s] * %s %s\n", chat, msg.Username, action)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "[%s] %s: %s\n", chat, msg.Username, msg.Text)
+	return err
+}
+
+func (r *plainRenderer) Status(w io.Writer, chat string, text string) error {
+	_, err := fmt.Fprintf(w, "*** [%s] %s\n", chat, text)
+	return err
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+const (
+	FormatPlain = "plain"
+	FormatColor = "color"
+	FormatJSON  = "json"
+)
+
+// Formats поддерживаемые форматы вывода сообщений
+var Formats = []string{FormatPlain, FormatColor, FormatJSON}
+
+// ActionPrefix начало текста действия, отправленного через /me
+const ActionPrefix = "/me "
+
+// Renderer выводит входящие сообщения и состояние потоков чатов.
+// chat метка чата: псевдоним или ID
+type Renderer interface {
+	Message(w io.Writer, chat string, msg model.Message) error
+	Status(w io.Writer, chat string, text string) error
+}
+
+// New создаёт Renderer формата, color false отключает ANSI-цвета
+func New(format string, color bool) (Renderer, error) {
+	switch format {
+	case FormatPlain:
+		return &plainRenderer{}, nil
+	case FormatColor:
+		return &colorRenderer{color: color}, nil
+	case FormatJSON:
+		return &jsonRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// palette цвета, различимые и на светлом, и на тёмном фоне
+var palette = []string{
+	"\033[31m", "\033[32m", "\033[33m", "\033[34m", "\033[35m", "\033[36m",
+	"\033[91m", "\033[92m", "\033[93m", "\033[94m", "\033[95m", "\033[96m",
+}
+
+const (
+	colorReset = "\033[0m"
+	colorDim   = "\033[2m"
+)
+
+// colorOf выбирает цвет по ключу, один ключ всегда получает один цвет
+func colorOf(key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return palette[h.Sum32()%uint32(len(palette))]
+}
@@ -0,0 +1,363 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+const (
+	indexExt = ".jsonl"
+	// markerExt файл-метка полностью построенного индекса, без неё индекс
+	// перестраивается: например, после обновления или сбоя записи
+	markerExt = ".built"
+	// indexVersion меняется вместе с форматом записей, чтобы старый индекс перестроился
+	indexVersion = "1"
+	dirPerm      = 0o700
+)
+
+var ErrNotBuilt = errors.New("search index is not built or is out of date")
+
+// Index инвертированный индекс локальной истории сообщений
+type Index interface {
+	history.Indexer
+	// Lookup возвращает положения сообщений, содержащих все terms и подходящих
+	// под фильтр, начиная с самых новых. Без terms подходят все сообщения
+	Lookup(username string, terms []string, filter Filter) ([]history.Ref, error)
+	// Rebuild строит индекс заново по всей истории пользователя
+	Rebuild(username string, store history.Store) error
+}
+
+var _ Index = (*fileIndex)(nil)
+
+// fileIndex дописывает по строке на сообщение в <dir>/<username>.jsonl,
+// а списки вхождений слов держит в памяти и дочитывает только новые строки
+type fileIndex struct {
+	dir string
+
+	mu    sync.Mutex
+	users map[string]*userIndex
+}
+
+type userIndex struct {
+	info     os.FileInfo
+	offset   int64
+	docs     []doc
+	refs     map[history.Ref]bool
+	postings map[string][]int
+}
+
+// doc запись индекса об одном сообщении
+type doc struct {
+	Ref   history.Ref `json:"ref"`
+	From  string      `json:"from"`
+	Time  time.Time   `json:"time"`
+	Terms []string    `json:"terms"`
+}
+
+func NewFileIndex(dir string) *fileIndex {
+	return &fileIndex{
+		dir:   dir,
+		users: make(map[string]*userIndex),
+	}
+}
+
+func (i *fileIndex) Index(username string, ref history.Ref, msg model.Message) error {
+	if err := i.index(username, ref, msg); err != nil {
+		// Сообщение не попало в индекс, следующий поиск перестроит его целиком
+		os.Remove(i.markerPath(username))
+		return err
+	}
+
+	return nil
+}
+
+func (i *fileIndex) index(username string, ref history.Ref, msg model.Message) error {
+	line, err := encodeDoc(ref, msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(i.dir, dirPerm); err != nil {
+		return err
+	}
+
+	path := i.path(username)
+
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, fileutil.FilePerm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (i *fileIndex) Lookup(username string, terms []string, filter Filter) ([]history.Ref, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	idx, err := i.load(username)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	if len(terms) == 0 {
+		ids = make([]int, len(idx.docs))
+		for id := range idx.docs {
+			ids[id] = id
+		}
+	} else {
+		ids = idx.intersect(terms)
+	}
+
+	matched := make([]doc, 0, len(ids))
+	for _, id := range ids {
+		d := idx.docs[id]
+		if filter.matches(d.Ref.ChatID, d.From, d.Time) {
+			matched = append(matched, d)
+		}
+	}
+
+	sort.SliceStable(matched, func(a, b int) bool { return matched[a].Time.After(matched[b].Time) })
+
+	refs := make([]history.Ref, len(matched))
+	for n, d := range matched {
+		refs[n] = d.Ref
+	}
+
+	return refs, nil
+}
+
+func (i *fileIndex) Rebuild(username string, store history.Store) error {
+	if err := os.MkdirAll(i.dir, dirPerm); err != nil {
+		return err
+	}
+
+	path := i.path(username)
+
+	// Блокировка держится от обхода истории до записи метки: сообщения,
+	// сохранённые во время обхода, дождутся её и допишутся в новый файл.
+	// Попавшие и в обход, и в дозапись отбрасываются при чтении по Ref
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var buf bytes.Buffer
+
+	err = store.Walk(username, func(ref history.Ref, msg model.Message) error {
+		line, err := encodeDoc(ref, msg)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := fileutil.WriteFileAtomic(path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	if err := fileutil.WriteFileAtomic(i.markerPath(username), []byte(indexVersion+"\n")); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	delete(i.users, username)
+	i.mu.Unlock()
+
+	return nil
+}
+
+func (i *fileIndex) path(username string) string {
+	return filepath.Join(i.dir, username+indexExt)
+}
+
+func (i *fileIndex) markerPath(username string) string {
+	return filepath.Join(i.dir, username+markerExt)
+}
+
+// built сообщает, что индекс построен по всей истории текущей версией
+func (i *fileIndex) built(username string) (bool, error) {
+	data, err := os.ReadFile(i.markerPath(username))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(string(data)) == indexVersion, nil
+}
+
+// load дочитывает строки, добавленные в индекс с прошлого вызова.
+// Если файл индекса был перестроен, он читается заново
+func (i *fileIndex) load(username string) (*userIndex, error) {
+	path := i.path(username)
+
+	built, err := i.built(username)
+	if err != nil {
+		return nil, err
+	}
+	if !built {
+		delete(i.users, username)
+		return nil, ErrNotBuilt
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotBuilt
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	idx, ok := i.users[username]
+	if !ok || !os.SameFile(idx.info, info) || info.Size() < idx.offset {
+		idx = &userIndex{refs: make(map[history.Ref]bool), postings: make(map[string][]int)}
+		i.users[username] = idx
+	}
+	idx.info = info
+
+	if info.Size() == idx.offset {
+		return idx, nil
+	}
+
+	unlock, err := fileutil.Lock(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(idx.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 || line[len(line)-1] != '\n' {
+			// Недописанная строка будет прочитана при следующем вызове
+			break
+		}
+		idx.offset += int64(len(line))
+
+		var d doc
+		if jsonErr := json.Unmarshal(line, &d); jsonErr == nil {
+			idx.add(d)
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	return idx, nil
+}
+
+func (idx *userIndex) add(d doc) {
+	if idx.refs[d.Ref] {
+		return
+	}
+	idx.refs[d.Ref] = true
+
+	id := len(idx.docs)
+	idx.docs = append(idx.docs, d)
+
+	for _, term := range d.Terms {
+		idx.postings[term] = append(idx.postings[term], id)
+	}
+}
+
+// intersect возвращает документы, содержащие все термы
+func (idx *userIndex) intersect(terms []string) []int {
+	lists := make([][]int, 0, len(terms))
+	for _, term := range terms {
+		list, ok := idx.postings[term]
+		if !ok {
+			return nil
+		}
+		lists = append(lists, list)
+	}
+
+	// Начинаем с самого короткого списка
+	sort.Slice(lists, func(a, b int) bool { return len(lists[a]) < len(lists[b]) })
+
+	result := lists[0]
+	for _, list := range lists[1:] {
+		result = intersectSorted(result, list)
+		if len(result) == 0 {
+			return nil
+		}
+	}
+
+	return result
+}
+
+func intersectSorted(a, b []int) []int {
+	result := make([]int, 0, len(a))
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return result
+}
+
+func encodeDoc(ref history.Ref, msg model.Message) ([]byte, error) {
+	line, err := json.Marshal(doc{
+		Ref:   ref,
+		From:  msg.Username,
+		Time:  msg.Time,
+		Terms: Tokenize(msg.Text),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+func TestSearchRebuildsIndexWithoutMarker(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// История, сохранённая до появления индекса
+	store := history.NewFileStore(filepath.Join(dir, "messages"), nil)
+	if err := store.Append("alice", model.Message{ChatID: "1", Username: "bob", Text: "deploy failed", Time: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	index := NewFileIndex(filepath.Join(dir, "index"))
+	store = history.NewFileStore(filepath.Join(dir, "messages"), index)
+	if err := store.Append("alice", model.Message{ChatID: "1", Username: "bob", Text: "deploy fixed", Time: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Search(index, store, "alice", Query{Words: []string{"deploy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("found %d messages, want both the old and the new one", len(results))
+	}
+
+	// Потерянная запись снимает метку, и следующий поиск снова перестраивает индекс
+	if err := os.Remove(index.markerPath("alice")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.Lookup("alice", []string{"deploy"}, Filter{}); !errors.Is(err, ErrNotBuilt) {
+		t.Fatalf("Lookup error = %v, want ErrNotBuilt", err)
+	}
+}
+
+// signalIndexer сообщает, что сообщение уже записано в историю и ждёт индексации
+type signalIndexer struct {
+	history.Indexer
+	written chan struct{}
+}
+
+func (s signalIndexer) Index(username string, ref history.Ref, msg model.Message) error {
+	s.written <- struct{}{}
+	return s.Indexer.Index(username, ref, msg)
+}
+
+// walkHook вызывает before и after вокруг обхода истории
+type walkHook struct {
+	history.Store
+	before, after func()
+}
+
+func (w walkHook) Walk(username string, fn func(history.Ref, model.Message) error) error {
+	w.before()
+	if err := w.Store.Walk(username, fn); err != nil {
+		return err
+	}
+	w.after()
+
+	return nil
+}
+
+func TestRebuildKeepsMessagesAppendedDuringRebuild(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	index := NewFileIndex(filepath.Join(dir, "index"))
+	written := make(chan struct{})
+	store := history.NewFileStore(filepath.Join(dir, "messages"), signalIndexer{Indexer: index, written: written})
+
+	var wg sync.WaitGroup
+	t.Cleanup(wg.Wait)
+
+	// appendInBackground сохраняет сообщение и возвращается, когда оно записано
+	// в историю, но ещё не проиндексировано
+	appendInBackground := func(text string, at time.Time) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Append("alice", model.Message{ChatID: "1", Username: "bob", Text: text, Time: at}); err != nil {
+				t.Error(err)
+			}
+		}()
+		<-written
+	}
+
+	hooked := walkHook{
+		Store:  store,
+		before: func() { appendInBackground("deploy started", now) },
+		after:  func() { appendInBackground("deploy finished", now.Add(time.Minute)) },
+	}
+	if err := index.Rebuild("alice", hooked); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	results, err := Search(index, store, "alice", Query{Words: []string{"deploy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("found %d messages, want each message once: %+v", len(results), results)
+	}
+}
@@ -0,0 +1,182 @@
+package search
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// Filter ограничения по метаданным сообщения. Нулевые значения не ограничивают поиск
+type Filter struct {
+	ChatID string
+	From   string
+	Since  time.Time
+	Until  time.Time
+}
+
+// Query поисковый запрос: все слова и фразы должны встретиться в сообщении
+type Query struct {
+	Words   []string
+	Phrases []string
+	// Regex если задан, сообщение должно ему соответствовать
+	Regex *regexp.Regexp
+	Filter
+	Limit int
+	// Context сколько соседних сообщений показывать с каждой стороны
+	Context int
+}
+
+// ParseQuery разбирает строку запроса: текст в двойных кавычках становится фразой,
+// остальное отдельными словами
+func ParseQuery(s string) (words, phrases []string) {
+	parts := strings.Split(s, `"`)
+
+	for i, part := range parts {
+		// Части с нечётными номерами находятся внутри кавычек
+		if i%2 == 1 && len(tokenSpans(part)) > 0 {
+			phrases = append(phrases, strings.TrimSpace(part))
+			continue
+		}
+
+		words = append(words, Tokenize(part)...)
+	}
+
+	return words, phrases
+}
+
+// ParseArgs разбирает запрос, уже разделённый на аргументы оболочкой или REPL:
+// аргумент из нескольких слов становится фразой, кавычки внутри аргумента
+// разбираются как в ParseQuery
+func ParseArgs(args []string) (words, phrases []string) {
+	for _, arg := range args {
+		if !strings.Contains(arg, `"`) && len(strings.Fields(arg)) > 1 {
+			phrases = append(phrases, strings.TrimSpace(arg))
+			continue
+		}
+
+		argWords, argPhrases := ParseQuery(arg)
+		words = append(words, argWords...)
+		phrases = append(phrases, argPhrases...)
+	}
+
+	return words, phrases
+}
+
+// terms слова, которые должны быть в индексе у подходящего сообщения
+func (q Query) terms() []string {
+	terms := append([]string(nil), q.Words...)
+	for _, phrase := range q.Phrases {
+		terms = append(terms, Tokenize(phrase)...)
+	}
+
+	return terms
+}
+
+func (q Query) empty() bool {
+	return len(q.terms()) == 0 && q.Regex == nil
+}
+
+// Match проверяет текст сообщения и возвращает отсортированные диапазоны совпадений
+func (q Query) Match(text string) ([]Range, bool) {
+	var ranges []Range
+
+	if len(q.Words) > 0 {
+		spans := tokenSpans(text)
+
+		for _, word := range q.Words {
+			found := false
+			for _, s := range spans {
+				if s.token == word {
+					ranges = append(ranges, s.Range)
+					found = true
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		}
+	}
+
+	for _, phrase := range q.Phrases {
+		found := phrasePattern(phrase).FindAllStringIndex(text, -1)
+		if len(found) == 0 {
+			return nil, false
+		}
+		ranges = appendIndexes(ranges, found)
+	}
+
+	if q.Regex != nil {
+		found := q.Regex.FindAllStringIndex(text, -1)
+		if len(found) == 0 {
+			return nil, false
+		}
+		ranges = appendIndexes(ranges, found)
+	}
+
+	return mergeRanges(ranges), true
+}
+
+// phrasePattern ищет слова фразы подряд без учёта регистра и разделителей между ними
+func phrasePattern(phrase string) *regexp.Regexp {
+	spans := tokenSpans(phrase)
+
+	words := make([]string, 0, len(spans))
+	for _, s := range spans {
+		words = append(words, regexp.QuoteMeta(phrase[s.Start:s.End]))
+	}
+
+	return regexp.MustCompile(`(?i)` + strings.Join(words, `[^\pL\pN]+`))
+}
+
+func appendIndexes(ranges []Range, indexes [][]int) []Range {
+	for _, idx := range indexes {
+		if idx[1] > idx[0] {
+			ranges = append(ranges, Range{Start: idx[0], End: idx[1]})
+		}
+	}
+
+	return ranges
+}
+
+func mergeRanges(ranges []Range) []Range {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+
+	merged := []Range{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+func (f Filter) matches(chatID, from string, t time.Time) bool {
+	if f.ChatID != "" && chatID != f.ChatID {
+		return false
+	}
+	if f.From != "" && from != f.From {
+		return false
+	}
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && t.After(f.Until) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,74 @@
+package search
+
+import (
+	"errors"
+
+	"github.com/Mobo140/chat-cli/internal/history"
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+// loadBatch сколько кандидатов читается из истории за раз
+const loadBatch = 100
+
+// Result найденное сообщение
+type Result struct {
+	Message model.Message
+	Matches []Range
+	// Context сообщения вокруг найденного, включая его самого
+	Context []model.Message
+	// Position позиция найденного сообщения в Context
+	Position int
+}
+
+// Search находит сообщения по индексу и проверяет кандидатов по тексту из истории.
+// Если индекс ещё не построен, он строится по всей истории
+func Search(index Index, store history.Store, username string, q Query) ([]Result, error) {
+	if q.empty() {
+		return nil, ErrEmptyQuery
+	}
+
+	refs, err := index.Lookup(username, q.terms(), q.Filter)
+	if errors.Is(err, ErrNotBuilt) {
+		if err := index.Rebuild(username, store); err != nil {
+			return nil, err
+		}
+		refs, err = index.Lookup(username, q.terms(), q.Filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+
+	for start := 0; start < len(refs); start += loadBatch {
+		end := min(start+loadBatch, len(refs))
+
+		messages, err := store.Get(username, refs[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for n, msg := range messages {
+			matches, ok := q.Match(msg.Text)
+			if !ok {
+				continue
+			}
+
+			result := Result{Message: msg, Matches: matches}
+
+			if q.Context > 0 {
+				result.Context, result.Position, err = store.Around(username, refs[start+n], q.Context)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			results = append(results, result)
+			if q.Limit > 0 && len(results) == q.Limit {
+				return results, nil
+			}
+		}
+	}
+
+	return results, nil
+}
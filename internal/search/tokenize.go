@@ -0,0 +1,92 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Range байтовый диапазон совпадения в тексте сообщения
+type Range struct {
+	Start int
+	End   int
+}
+
+type span struct {
+	Range
+	token string
+}
+
+// Tokenize разбивает текст на слова в нижнем регистре без повторов
+func Tokenize(text string) []string {
+	seen := make(map[string]struct{})
+
+	var tokens []string
+	for _, s := range tokenSpans(text) {
+		if _, ok := seen[s.token]; ok {
+			continue
+		}
+		seen[s.token] = struct{}{}
+		tokens = append(tokens, s.token)
+	}
+
+	return tokens
+}
+
+// tokenSpans находит слова (последовательности букв и цифр) и их положение в тексте
+func tokenSpans(text string) []span {
+	var (
+		spans []span
+		start = -1
+	)
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			spans = append(spans, newSpan(text, start, i))
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, newSpan(text, start, len(text)))
+	}
+
+	return spans
+}
+
+func newSpan(text string, start, end int) span {
+	return span{Range: Range{Start: start, End: end}, token: strings.ToLower(text[start:end])}
+}
+
+// Highlight оборачивает совпадения в before и after
+func Highlight(text string, ranges []Range, before, after string) string {
+	if len(ranges) == 0 {
+		return text
+	}
+
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, r := range ranges {
+		if r.Start < last || r.End > len(text) || !utf8.ValidString(text[r.Start:r.End]) {
+			continue
+		}
+
+		b.WriteString(text[last:r.Start])
+		b.WriteString(before)
+		b.WriteString(text[r.Start:r.End])
+		b.WriteString(after)
+		last = r.End
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package session
+
+import (
+	"encoding/json"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var _ Codec = (*plainCodec)(nil)
+
+type plainCodec struct{}
+
+func NewPlainCodec() *plainCodec {
+	return &plainCodec{}
+}
+
+func (c *plainCodec) Encode(session *model.Session) ([]byte, error) {
+	return json.MarshalIndent(session, "", "  ")
+}
+
+func (c *plainCodec) Decode(data []byte) (*model.Session, error) {
+	var s model.Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
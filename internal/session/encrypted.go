@@ -0,0 +1,207 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+const (
+	encryptedVersion = 1
+	kdfPBKDF2SHA256  = "pbkdf2-sha256"
+	kdfIterations    = 600000
+	// kdfMaxIterations ограничивает число итераций из заголовка, чтобы
+	// подменённый файл не заставил выводить ключ часами
+	kdfMaxIterations = 10 * kdfIterations
+	saltSize         = 16
+	keySize          = 32
+)
+
+var _ Codec = (*encryptedCodec)(nil)
+
+// ErrNotEncrypted файл сессии не зашифрован. Такие файлы принимаются только
+// при переносе из старых версий, иначе их мог подложить кто угодно
+var ErrNotEncrypted = errors.New("session file is not encrypted, please login again")
+
+// encryptedFile формат зашифрованного файла сессии: параметры KDF
+// хранятся в заголовке и аутентифицируются вместе с шифротекстом
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+type encryptedCodec struct {
+	passphrase []byte
+	plain      *plainCodec
+
+	mu   sync.Mutex
+	salt []byte
+	keys map[string][]byte
+}
+
+// NewEncryptedCodec шифрует сессии AES-256-GCM ключом, выведенным из passphrase
+func NewEncryptedCodec(passphrase string) (*encryptedCodec, error) {
+	if passphrase == "" {
+		return nil, errors.New("session passphrase is empty")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return &encryptedCodec{
+		passphrase: []byte(passphrase),
+		plain:      NewPlainCodec(),
+		salt:       salt,
+		keys:       make(map[string][]byte),
+	}, nil
+}
+
+func (c *encryptedCodec) Encode(session *model.Session) ([]byte, error) {
+	plaintext, err := c.plain.Encode(session)
+	if err != nil {
+		return nil, err
+	}
+
+	file := encryptedFile{
+		Version:    encryptedVersion,
+		KDF:        kdfPBKDF2SHA256,
+		Iterations: kdfIterations,
+		Salt:       c.salt,
+	}
+
+	aead, err := c.aead(file)
+	if err != nil {
+		return nil, err
+	}
+
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return nil, err
+	}
+
+	ad, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, ad)
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+func (c *encryptedCodec) Decode(data []byte) (*model.Session, error) {
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if file.Version == 0 {
+		return nil, ErrNotEncrypted
+	}
+
+	if file.Version != encryptedVersion {
+		return nil, fmt.Errorf("unsupported session file version: %d", file.Version)
+	}
+	if file.KDF != kdfPBKDF2SHA256 {
+		return nil, fmt.Errorf("unsupported session kdf: %s", file.KDF)
+	}
+
+	aead, err := c.aead(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := file.Ciphertext
+	file.Ciphertext = nil
+
+	ad, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid session nonce")
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, ciphertext, ad)
+	if err != nil {
+		return nil, errors.New("failed to decrypt session: wrong passphrase or corrupted file")
+	}
+
+	return c.plain.Decode(plaintext)
+}
+
+func (c *encryptedCodec) aead(file encryptedFile) (cipher.AEAD, error) {
+	if file.Iterations < kdfIterations || file.Iterations > kdfMaxIterations || len(file.Salt) == 0 {
+		return nil, errors.New("invalid session kdf parameters")
+	}
+
+	block, err := aes.NewCipher(c.key(file.Salt, file.Iterations))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// key кэширует выведенные ключи, чтобы не запускать KDF на каждое чтение
+func (c *encryptedCodec) key(salt []byte, iterations int) []byte {
+	id := fmt.Sprintf("%x:%d", salt, iterations)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[id]; ok {
+		return key
+	}
+
+	key := pbkdf2SHA256(c.passphrase, salt, iterations, keySize)
+	c.keys[id] = key
+
+	return key
+}
+
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = u[:0]
+			u = prf.Sum(u)
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+
+	return dk[:keyLen]
+}
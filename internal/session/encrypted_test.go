@@ -0,0 +1,202 @@
+package session
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+func newTestCodec(t *testing.T, passphrase string) *encryptedCodec {
+	t.Helper()
+
+	codec, err := NewEncryptedCodec(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return codec
+}
+
+var testSession = &model.Session{Username: "bob", AccessToken: "access", RefreshToken: "refresh"}
+
+// tamper меняет заголовок или шифротекст зашифрованного файла
+func tamper(t *testing.T, data []byte, fn func(file *encryptedFile)) []byte {
+	t.Helper()
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	fn(&file)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestEncryptedCodecRoundTrip(t *testing.T) {
+	codec := newTestCodec(t, "secret")
+
+	data, err := codec.Encode(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testSession.RefreshToken) {
+		t.Fatalf("encoded session contains the refresh token:\n%s", data)
+	}
+
+	got, err := codec.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != testSession.Username || got.AccessToken != testSession.AccessToken ||
+		got.RefreshToken != testSession.RefreshToken {
+		t.Fatalf("decoded %+v, want %+v", got, testSession)
+	}
+
+	// Другой процесс с той же passphrase выводит свой ключ по соли из заголовка
+	if _, err := newTestCodec(t, "secret").Decode(data); err != nil {
+		t.Fatalf("decode with a new codec: %v", err)
+	}
+}
+
+func TestEncryptedCodecRejectsWrongPassphrase(t *testing.T) {
+	data, err := newTestCodec(t, "secret").Encode(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTestCodec(t, "other").Decode(data); err == nil {
+		t.Fatal("decoded a session with the wrong passphrase")
+	}
+}
+
+func TestEncryptedCodecRejectsTamperedFile(t *testing.T) {
+	codec := newTestCodec(t, "secret")
+
+	data, err := codec.Encode(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func(file *encryptedFile)
+	}{
+		{"ciphertext", func(f *encryptedFile) { f.Ciphertext[0] ^= 1 }},
+		{"nonce", func(f *encryptedFile) { f.Nonce[0] ^= 1 }},
+		{"short nonce", func(f *encryptedFile) { f.Nonce = f.Nonce[:4] }},
+		{"too few iterations", func(f *encryptedFile) { f.Iterations = 1 }},
+		{"too many iterations", func(f *encryptedFile) { f.Iterations = 1 << 40 }},
+		{"kdf", func(f *encryptedFile) { f.KDF = "none" }},
+		{"version", func(f *encryptedFile) { f.Version = 2 }},
+	}
+
+	for _, tt := range tests {
+		if _, err := codec.Decode(tamper(t, data, tt.fn)); err == nil {
+			t.Errorf("%s: decoded a tampered session", tt.name)
+		}
+	}
+}
+
+func TestEncryptedCodecRejectsPlaintext(t *testing.T) {
+	data, err := NewPlainCodec().Encode(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTestCodec(t, "secret").Decode(data); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("Decode error = %v, want ErrNotEncrypted", err)
+	}
+}
+
+func TestMigratePlaintextEncryptsLegacySession(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "session"), newTestCodec(t, "secret"))
+
+	data, err := NewPlainCodec().Encode(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.Path("bob"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load("bob"); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("Load before migration error = %v, want ErrNotEncrypted", err)
+	}
+
+	if err := store.MigratePlaintext("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := os.ReadFile(store.Path("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encrypted), testSession.RefreshToken) {
+		t.Fatalf("migrated session is not encrypted:\n%s", encrypted)
+	}
+
+	got, err := store.Load("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RefreshToken != testSession.RefreshToken {
+		t.Fatalf("migrated refresh token = %q, want %q", got.RefreshToken, testSession.RefreshToken)
+	}
+
+	// Повторный перенос уже зашифрованного файла ничего не меняет
+	if err := store.MigratePlaintext("bob"); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(store.Path("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(encrypted) {
+		t.Fatal("migration rewrote an encrypted session")
+	}
+}
+
+func TestMigratePlaintextRejectsForeignSession(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "session"), newTestCodec(t, "secret"))
+
+	data, err := NewPlainCodec().Encode(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.Path("alice"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.MigratePlaintext("alice"); err == nil {
+		t.Fatal("migrated a session file of another user")
+	}
+}
+
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		iterations int
+		want       string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA256([]byte("password"), []byte("salt"), tt.iterations, keySize))
+		if got != tt.want {
+			t.Errorf("pbkdf2SHA256 with %d iterations = %s, want %s", tt.iterations, got, tt.want)
+		}
+	}
+}
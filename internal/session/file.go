@@ -0,0 +1,258 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/gofrs/flock"
+)
+
+const refresherSuffix = ".refresher"
+
+var (
+	_ Store    = (*fileStore)(nil)
+	_ Locator  = (*fileStore)(nil)
+	_ Elector  = (*fileStore)(nil)
+	_ Migrator = (*fileStore)(nil)
+)
+
+type fileStore struct {
+	basePath string
+	codec    Codec
+}
+
+// NewFileStore хранит сессии в файлах вида <basePath>.<username>.
+// Файлы можно безопасно разделять между несколькими процессами
+func NewFileStore(basePath string, codec Codec) *fileStore {
+	return &fileStore{basePath: basePath, codec: codec}
+}
+
+// Path возвращает путь к файлу сессии пользователя
+func (s *fileStore) Path(username string) string {
+	return fmt.Sprintf("%s.%s", s.basePath, username)
+}
+
+// path проверяет имя пользователя, чтобы файл сессии не оказался вне каталога
+func (s *fileStore) path(username string) (string, error) {
+	if err := fileutil.ValidName(username); err != nil {
+		return "", fmt.Errorf("invalid username: %w", err)
+	}
+
+	return s.Path(username), nil
+}
+
+func (s *fileStore) Load(username string) (*model.Session, error) {
+	path, err := s.path(username)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	unlock, err := fileutil.Lock(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return s.read(path)
+}
+
+func (s *fileStore) Save(session *model.Session) error {
+	path, err := s.path(session.Username)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.write(path, session)
+}
+
+func (s *fileStore) Update(username string, fn func(session *model.Session) error) error {
+	path, err := s.path(username)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	session, err := s.read(path)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(session); err != nil {
+		return err
+	}
+
+	return s.write(path, session)
+}
+
+func (s *fileStore) Delete(username string) error {
+	path, err := s.path(username)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		err = ErrNotFound
+	}
+
+	// Файлы блокировок, которые держит другой процесс, остаются на месте
+	for _, suffix := range []string{fileutil.LockSuffix, refresherSuffix} {
+		if rmErr := fileutil.RemoveStaleLock(path + suffix); rmErr != nil {
+			return rmErr
+		}
+	}
+
+	return err
+}
+
+func (s *fileStore) List() ([]string, error) {
+	prefix := filepath.Base(s.basePath) + "."
+
+	entries, err := os.ReadDir(filepath.Dir(s.basePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var usernames []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasSuffix(name, fileutil.LockSuffix) || strings.HasSuffix(name, refresherSuffix) ||
+			strings.Contains(name, fileutil.TempMarker) {
+			continue
+		}
+
+		usernames = append(usernames, strings.TrimPrefix(name, prefix))
+	}
+	sort.Strings(usernames)
+
+	return usernames, nil
+}
+
+// MigratePlaintext перезаписывает незашифрованный файл сессии в формате
+// хранилища. Файлы, которые хранилище уже читает, не меняются
+func (s *fileStore) MigratePlaintext(username string) error {
+	path, err := s.path(username)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fileutil.Lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.codec.Decode(data); !errors.Is(err, ErrNotEncrypted) {
+		return err
+	}
+
+	session, err := NewPlainCodec().Decode(data)
+	if err != nil {
+		return err
+	}
+	if session.Username != username {
+		return fmt.Errorf("session file of %q belongs to %q", username, session.Username)
+	}
+
+	return s.write(path, session)
+}
+
+// TryAcquireRefresher захватывает роль обновляющего токены процесса.
+// Блокировка снимается при вызове release или завершении процесса
+func (s *fileStore) TryAcquireRefresher(username string) (func(), bool, error) {
+	path, err := s.path(username)
+	if err != nil {
+		return nil, false, err
+	}
+
+	lock := flock.New(path+refresherSuffix, flock.SetPermissions(fileutil.FilePerm))
+
+	ok, err := lock.TryLock()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	return func() { lock.Unlock() }, true, nil
+}
+
+func (s *fileStore) ModTime(username string) (time.Time, error) {
+	path, err := s.path(username)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
+
+func (s *fileStore) read(path string) (*model.Session, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.codec.Decode(data)
+}
+
+func (s *fileStore) write(path string, session *model.Session) error {
+	data, err := s.codec.Encode(session)
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(path, data)
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Mobo140/chat-cli/internal/fileutil"
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+func exists(t *testing.T, path string) bool {
+	t.Helper()
+
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return true
+}
+
+func TestDeleteRemovesOnlyStaleLocks(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "session"), NewPlainCodec())
+	if err := store.Save(&model.Session{Username: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Токены bob всё ещё обновляет другой процесс
+	release, ok, err := store.TryAcquireRefresher("bob")
+	if err != nil || !ok {
+		t.Fatalf("TryAcquireRefresher = %v, %v", ok, err)
+	}
+
+	if err := store.Delete("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := store.Path("bob")
+	if exists(t, path) {
+		t.Fatal("session file was not deleted")
+	}
+	if exists(t, path+fileutil.LockSuffix) {
+		t.Fatal("stale lock file was not deleted")
+	}
+	if !exists(t, path+refresherSuffix) {
+		t.Fatal("held refresher lock file was deleted")
+	}
+
+	release()
+
+	if err := store.Delete("bob"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Delete error = %v, want ErrNotFound", err)
+	}
+	if exists(t, path+refresherSuffix) {
+		t.Fatal("released refresher lock file was not deleted")
+	}
+}
+
+func TestFileStoreRejectsUsernamesOutsideDir(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "session"), NewPlainCodec())
+
+	for _, username := range []string{"", ".", "..", "x/../../y", "a/b", `a\b`} {
+		if err := store.Save(&model.Session{Username: username}); !errors.Is(err, fileutil.ErrInvalidName) {
+			t.Errorf("Save(%q) error = %v, want ErrInvalidName", username, err)
+		}
+		if _, err := store.Load(username); !errors.Is(err, fileutil.ErrInvalidName) {
+			t.Errorf("Load(%q) error = %v, want ErrInvalidName", username, err)
+		}
+	}
+}
+
+func TestTryAcquireRefresherElectsOneProcess(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "session")
+	// Каждое хранилище открывает свой файл блокировки, как отдельный процесс
+	first := NewFileStore(base, NewPlainCodec())
+	second := NewFileStore(base, NewPlainCodec())
+
+	release, ok, err := first.TryAcquireRefresher("bob")
+	if err != nil || !ok {
+		t.Fatalf("first TryAcquireRefresher = %v, %v", ok, err)
+	}
+
+	if _, ok, err := second.TryAcquireRefresher("bob"); err != nil || ok {
+		t.Fatalf("second TryAcquireRefresher while held = %v, %v", ok, err)
+	}
+
+	// Роль выбирается для каждого пользователя отдельно
+	releaseAlice, ok, err := second.TryAcquireRefresher("alice")
+	if err != nil || !ok {
+		t.Fatalf("TryAcquireRefresher for another user = %v, %v", ok, err)
+	}
+	releaseAlice()
+
+	release()
+
+	releaseSecond, ok, err := second.TryAcquireRefresher("bob")
+	if err != nil || !ok {
+		t.Fatalf("second TryAcquireRefresher after release = %v, %v", ok, err)
+	}
+	releaseSecond()
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var _ Store = (*memoryStore)(nil)
+
+type memoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]model.Session
+}
+
+func NewMemoryStore() *memoryStore {
+	return &memoryStore{sessions: make(map[string]model.Session)}
+}
+
+func (s *memoryStore) Load(username string) (*model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[username]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &session, nil
+}
+
+func (s *memoryStore) Save(session *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.Username] = *session
+
+	return nil
+}
+
+func (s *memoryStore) Update(username string, fn func(session *model.Session) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[username]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if err := fn(&session); err != nil {
+		return err
+	}
+	s.sessions[username] = session
+
+	return nil
+}
+
+func (s *memoryStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[username]; !ok {
+		return ErrNotFound
+	}
+	delete(s.sessions, username)
+
+	return nil
+}
+
+func (s *memoryStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usernames := make([]string, 0, len(s.sessions))
+	for username := range s.sessions {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	return usernames, nil
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/model"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+// Store хранилище пользовательских сессий
+type Store interface {
+	Load(username string) (*model.Session, error)
+	Save(session *model.Session) error
+	// Update атомарно читает, изменяет и сохраняет сессию, чтобы не затереть
+	// изменения, сделанные другим процессом между чтением и записью
+	Update(username string, fn func(session *model.Session) error) error
+	Delete(username string) error
+	List() ([]string, error)
+}
+
+// Codec сериализует сессию в содержимое файла и обратно
+type Codec interface {
+	Encode(session *model.Session) ([]byte, error)
+	Decode(data []byte) (*model.Session, error)
+}
+
+// Locator реализуют хранилища, которые держат сессии в файлах
+type Locator interface {
+	Path(username string) string
+}
+
+// Migrator реализуют хранилища, которые перекодируют незашифрованные сессии,
+// перенесённые из файлов старых версий
+type Migrator interface {
+	MigratePlaintext(username string) error
+}
+
+// Elector реализуют хранилища, разделяемые несколькими процессами: только
+// один процесс обновляет токены пользователя, остальные перечитывают файл,
+// когда меняется время его изменения
+type Elector interface {
+	TryAcquireRefresher(username string) (release func(), ok bool, err error)
+	ModTime(username string) (time.Time, error)
+}
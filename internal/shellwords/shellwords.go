@@ -0,0 +1,254 @@
+package shellwords
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrIncomplete строка обрывается внутри кавычек, после \ или до конца
+// heredoc-блока; разбор нужно повторить, дописав следующую строку ввода
+var ErrIncomplete = errors.New("incomplete input")
+
+// ErrMissingDelimiter после << нет слова, которым заканчивается heredoc-блок
+var ErrMissingDelimiter = errors.New("missing heredoc delimiter after <<")
+
+// Split разбирает ввод на аргументы по правилам, близким к POSIX shell:
+//   - пробелы и табуляции разделяют аргументы, кроме как внутри кавычек;
+//   - 'текст' берётся как есть, в "тексте" \ экранирует только " \ $ `;
+//   - \ вне кавычек экранирует следующий символ, \ в конце строки продолжает её;
+//   - <<EOF становится одним аргументом из строк до строки EOF
+func Split(input string) ([]string, error) {
+	p := &parser{in: []rune(input)}
+
+	return p.parse()
+}
+
+type parser struct {
+	in  []rune
+	pos int
+
+	args []string
+	// heredocs блоки текущей строки, тело которых начинается со следующей
+	heredocs []heredoc
+
+	word   strings.Builder
+	inWord bool
+}
+
+type heredoc struct {
+	delimiter string
+	arg       int
+}
+
+func (p *parser) parse() ([]string, error) {
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+
+		switch {
+		case c == '\n':
+			p.flush()
+			p.pos++
+
+			if err := p.readHeredocs(); err != nil {
+				return nil, err
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			p.flush()
+			p.pos++
+		case c == '\\':
+			if p.pos+1 >= len(p.in) {
+				return nil, ErrIncomplete
+			}
+
+			next := p.in[p.pos+1]
+			p.pos += 2
+
+			// Перенос строки после \ только продолжает её
+			if next != '\n' {
+				p.write(next)
+			}
+		case c == '\'':
+			if err := p.singleQuoted(); err != nil {
+				return nil, err
+			}
+		case c == '"':
+			if err := p.doubleQuoted(); err != nil {
+				return nil, err
+			}
+		case c == '<' && !p.inWord && p.hasPrefix("<<"):
+			p.pos += 2
+
+			delimiter, err := p.delimiter()
+			if err != nil {
+				return nil, err
+			}
+
+			p.heredocs = append(p.heredocs, heredoc{delimiter: delimiter, arg: len(p.args)})
+			p.args = append(p.args, "")
+		default:
+			p.write(c)
+			p.pos++
+		}
+	}
+
+	p.flush()
+
+	if len(p.heredocs) > 0 {
+		return nil, ErrIncomplete
+	}
+
+	return p.args, nil
+}
+
+func (p *parser) singleQuoted() error {
+	p.inWord = true
+
+	for i := p.pos + 1; i < len(p.in); i++ {
+		if p.in[i] == '\'' {
+			p.word.WriteString(string(p.in[p.pos+1 : i]))
+			p.pos = i + 1
+			return nil
+		}
+	}
+
+	return ErrIncomplete
+}
+
+func (p *parser) doubleQuoted() error {
+	p.inWord = true
+	p.pos++
+
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+
+		switch c {
+		case '"':
+			p.pos++
+			return nil
+		case '\\':
+			if p.pos+1 >= len(p.in) {
+				return ErrIncomplete
+			}
+
+			switch next := p.in[p.pos+1]; next {
+			case '"', '\\', '$', '`':
+				p.word.WriteRune(next)
+			case '\n':
+			default:
+				p.word.WriteRune(c)
+				p.word.WriteRune(next)
+			}
+			p.pos += 2
+		default:
+			p.word.WriteRune(c)
+			p.pos++
+		}
+	}
+
+	return ErrIncomplete
+}
+
+// delimiter читает слово после <<, кавычки вокруг него снимаются
+func (p *parser) delimiter() (string, error) {
+	for p.pos < len(p.in) && (p.in[p.pos] == ' ' || p.in[p.pos] == '\t') {
+		p.pos++
+	}
+
+	start := p.pos
+	for p.pos < len(p.in) && !isSpace(p.in[p.pos]) {
+		p.pos++
+	}
+
+	delimiter := strings.Trim(string(p.in[start:p.pos]), `'"`)
+	if delimiter == "" {
+		return "", ErrMissingDelimiter
+	}
+
+	return delimiter, nil
+}
+
+// readHeredocs читает тела блоков, начатых на только что закончившейся строке
+func (p *parser) readHeredocs() error {
+	for _, h := range p.heredocs {
+		var body []string
+
+		for {
+			if p.pos >= len(p.in) {
+				return ErrIncomplete
+			}
+
+			end := p.pos
+			for end < len(p.in) && p.in[end] != '\n' {
+				end++
+			}
+
+			line := string(p.in[p.pos:end])
+			// Последняя строка ввода может быть без перевода строки
+			if end == len(p.in) && line != h.delimiter {
+				return ErrIncomplete
+			}
+
+			p.pos = end + 1
+			if p.pos > len(p.in) {
+				p.pos = len(p.in)
+			}
+
+			if line == h.delimiter {
+				break
+			}
+			body = append(body, line)
+		}
+
+		p.args[h.arg] = strings.Join(body, "\n")
+	}
+
+	p.heredocs = nil
+
+	return nil
+}
+
+func (p *parser) write(c rune) {
+	p.word.WriteRune(c)
+	p.inWord = true
+}
+
+func (p *parser) flush() {
+	if !p.inWord {
+		return
+	}
+
+	p.args = append(p.args, p.word.String())
+	p.word.Reset()
+	p.inWord = false
+}
+
+func (p *parser) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(string(p.in[p.pos:]), prefix)
+}
+
+func isSpace(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// Join собирает аргументы в строку, которую Split разберёт обратно в те же аргументы
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// quote берёт аргумент в одинарные кавычки, если в нём есть специальные символы
+func quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+
+	if !strings.ContainsAny(arg, " \t\r\n'\"\\<$`") {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
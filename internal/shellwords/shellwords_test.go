@@ -0,0 +1,116 @@
+package shellwords
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"words", "send-message -c 1 -m hi", []string{"send-message", "-c", "1", "-m", "hi"}},
+		{"extra spaces", "  a \t b  \r", []string{"a", "b"}},
+		{"empty", "", nil},
+		{"single quotes", `a 'b  c' 'd\e "f"'`, []string{"a", "b  c", `d\e "f"`}},
+		{"double quotes", `"b  c" "it's"`, []string{"b  c", "it's"}},
+		{"adjacent quotes", `'a'"b"c`, []string{"abc"}},
+		{"empty quotes", `'' ""`, []string{"", ""}},
+		{"escapes in double quotes", `"\"q\" \\ \$ \` + "`" + ` \x"`, []string{`"q" \ $ ` + "` " + `\x`}},
+		{"escaped space", `a\ b \'c`, []string{"a b", "'c"}},
+		{"continuation between words", "a \\\nb", []string{"a", "b"}},
+		{"continuation inside word", "a\\\nb", []string{"ab"}},
+		{"continuation in double quotes", "\"a\\\nb\"", []string{"ab"}},
+		{"newline in single quotes", "'a\nb'", []string{"a\nb"}},
+		{"heredoc", "send <<EOF\nline 1\n  line 2\nEOF", []string{"send", "line 1\n  line 2"}},
+		{"heredoc with trailing newline", "send <<EOF\nhi\nEOF\n", []string{"send", "hi"}},
+		{"empty heredoc", "send <<EOF\nEOF", []string{"send", ""}},
+		{"heredoc keeps quotes and escapes", "send <<EOF\n'a' \"b\" \\c $d\nEOF", []string{"send", `'a' "b" \c $d`}},
+		{"quoted delimiter", "send << 'END'\nEOF\nEND", []string{"send", "EOF"}},
+		{"several heredocs", "x <<A -m <<B\na1\nA\nb1\nb2\nB", []string{"x", "a1", "-m", "b1\nb2"}},
+		{"words after heredoc", "x <<EOF y\nbody\nEOF\nz", []string{"x", "body", "y", "z"}},
+		{"delimiter must be the whole line", "x <<EOF\n EOF\nEOF", []string{"x", " EOF"}},
+		{"<< inside a word", "a<<b", []string{"a<<b"}},
+	}
+
+	for _, tt := range tests {
+		got, err := Split(tt.input)
+		if err != nil {
+			t.Errorf("%s: Split(%q) error: %v", tt.name, tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Split(%q) = %q, want %q", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"open single quote", "a 'b", ErrIncomplete},
+		{"open double quote", `a "b`, ErrIncomplete},
+		{"trailing backslash", `a \`, ErrIncomplete},
+		{"trailing backslash in double quotes", `"a \`, ErrIncomplete},
+		{"heredoc without body", "send <<EOF", ErrIncomplete},
+		{"heredoc without end", "send <<EOF\nline", ErrIncomplete},
+		{"second heredoc without end", "x <<A <<B\na\nA\nb\n", ErrIncomplete},
+		{"no delimiter", "send <<", ErrMissingDelimiter},
+		{"no delimiter before newline", "send << \nbody", ErrMissingDelimiter},
+		{"empty quoted delimiter", "send <<''", ErrMissingDelimiter},
+	}
+
+	for _, tt := range tests {
+		if _, err := Split(tt.input); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Split(%q) error = %v, want %v", tt.name, tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"send-message", "-m", "hi"}, "send-message -m hi"},
+		{[]string{"a b", ""}, "'a b' ''"},
+		{[]string{"it's"}, `'it'\''s'`},
+		{[]string{"<<EOF", "$HOME"}, "'<<EOF' '$HOME'"},
+	}
+
+	for _, tt := range tests {
+		if got := Join(tt.args); got != tt.want {
+			t.Errorf("Join(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestJoinSplitRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"a"},
+		{"", "b", ""},
+		{"a b", "it's", "'", `back\slash`, `"q"`, "tab\there"},
+		{"multi\nline", "trailing\n", "\r\n"},
+		{"$HOME", "`cmd`", "<<EOF", "a<<b"},
+		{"привет мир", "🙂"},
+	}
+
+	for _, args := range tests {
+		line := Join(args)
+
+		got, err := Split(line)
+		if err != nil {
+			t.Errorf("Split(Join(%q)) = Split(%q) error: %v", args, line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Errorf("Split(Join(%q)) = %q via %q", args, got, line)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+)
+
+type fingerprint [sha256.Size]byte
+
+type seenMessage struct {
+	fp fingerprint
+	at time.Time
+}
+
+// dedupe отбрасывает сообщения, которые сервер повторно присылает после
+// переподключения. Сообщение считается повтором, если в течение окна после
+// переподключения пришло сообщение с тем же автором, текстом и временем на
+// сервере, что и одно из недавно полученных. Одинаковые сообщения учитываются
+// по количеству. Без времени от сервера сравниваются только автор и текст
+type dedupe struct {
+	mu sync.Mutex
+
+	size   int
+	ttl    time.Duration
+	recent []seenMessage
+	counts map[fingerprint]int
+
+	replayUntil time.Time
+	replay      map[fingerprint]int
+}
+
+func newDedupe(size int, ttl time.Duration) *dedupe {
+	return &dedupe{
+		size:   size,
+		ttl:    ttl,
+		counts: make(map[fingerprint]int),
+	}
+}
+
+// startReplay начинает окно, в котором повторы уже полученных сообщений отбрасываются
+func (d *dedupe) startReplay(until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire(time.Now())
+
+	d.replayUntil = until
+	d.replay = make(map[fingerprint]int, len(d.counts))
+	for fp, n := range d.counts {
+		d.replay[fp] = n
+	}
+}
+
+// accept возвращает false для повторно присланного сообщения
+func (d *dedupe) accept(msg *chat.Message) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	fp := fingerprintOf(msg)
+
+	if now.Before(d.replayUntil) && d.replay[fp] > 0 {
+		d.replay[fp]--
+		return false
+	}
+
+	d.expire(now)
+
+	d.recent = append(d.recent, seenMessage{fp: fp, at: now})
+	d.counts[fp]++
+	if len(d.recent) > d.size {
+		d.forget(d.recent[0])
+		d.recent = d.recent[1:]
+	}
+
+	return true
+}
+
+func (d *dedupe) expire(now time.Time) {
+	n := 0
+	for n < len(d.recent) && now.Sub(d.recent[n].at) > d.ttl {
+		d.forget(d.recent[n])
+		n++
+	}
+	d.recent = d.recent[n:]
+}
+
+func (d *dedupe) forget(m seenMessage) {
+	d.counts[m.fp]--
+	if d.counts[m.fp] <= 0 {
+		delete(d.counts, m.fp)
+	}
+}
+
+func fingerprintOf(msg *chat.Message) fingerprint {
+	h := sha256.New()
+	h.Write([]byte(msg.ChatID))
+	h.Write([]byte{0})
+	h.Write([]byte(msg.Username))
+	h.Write([]byte{0})
+	h.Write([]byte(msg.Text))
+	if !msg.Time.IsZero() {
+		// Повтор того же текста позже отличается временем и не отбрасывается
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Time.UTC().Format(time.RFC3339Nano)))
+	}
+
+	var fp fingerprint
+	copy(fp[:], h.Sum(nil))
+
+	return fp
+}
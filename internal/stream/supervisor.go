@@ -0,0 +1,273 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/backoff"
+	"github.com/Mobo140/chat-cli/internal/clients"
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// connectGrace поток без ошибки дольше этого времени считается подключённым
+	connectGrace = 3 * time.Second
+	// replayWindow сколько после переподключения отбрасываются повторы сообщений
+	replayWindow = 10 * time.Second
+	dedupeSize   = 1000
+	dedupeTTL    = time.Hour
+)
+
+var ErrStalled = errors.New("no messages received within stall timeout")
+
+type State int
+
+const (
+	StateConnecting State = iota
+	StateConnected
+	StateReconnecting
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// Event изменение состояния потока
+type Event struct {
+	State State
+	// Attempt номер попытки переподключения, 0 для первого подключения
+	Attempt int
+	// RetryIn задержка до следующей попытки для StateReconnecting
+	RetryIn time.Duration
+	// Err причина разрыва или остановки
+	Err error
+}
+
+// Supervisor держит поток ConnectChat открытым: переподключается с
+// экспоненциальной задержкой, перезапускает зависший поток и отбрасывает
+// сообщения, повторно присланные после переподключения
+type Supervisor struct {
+	client   clients.ChatServiceClient
+	cfg      config.StreamConfig
+	chatID   string
+	username string
+	handler  func(*chat.Message)
+	onEvent  func(Event)
+	dedupe   *dedupe
+}
+
+func NewSupervisor(
+	client clients.ChatServiceClient,
+	cfg config.StreamConfig,
+	chatID, username string,
+	handler func(*chat.Message),
+	onEvent func(Event),
+) *Supervisor {
+	return &Supervisor{
+		client:   client,
+		cfg:      cfg,
+		chatID:   chatID,
+		username: username,
+		handler:  handler,
+		onEvent:  onEvent,
+		dedupe:   newDedupe(dedupeSize, dedupeTTL),
+	}
+}
+
+// Run возвращает nil после отмены ctx или ошибку, при которой переподключаться бессмысленно
+func (s *Supervisor) Run(ctx context.Context) error {
+	retry := backoff.New(s.cfg.ReconnectMinDelay(), s.cfg.ReconnectMaxDelay())
+	attempt := 0
+	resumed := false
+
+	s.emit(Event{State: StateConnecting})
+
+	for {
+		connected, err := s.connect(ctx, attempt, resumed)
+		resumed = false
+		if ctx.Err() != nil {
+			s.emit(Event{State: StateStopped})
+			return nil
+		}
+		if !retryable(err) {
+			s.emit(Event{State: StateStopped, Err: err})
+			return err
+		}
+
+		if connected {
+			retry.Reset()
+			attempt = 0
+		}
+
+		// Тихий чат тоже упирается в StallTimeout, поэтому подключённый поток
+		// переоткрывается сразу и без событий. Мёртвое соединение при этом
+		// обнаружит keepalive, и поток переподключится как обычно
+		if connected && errors.Is(err, ErrStalled) {
+			resumed = true
+			s.dedupe.startReplay(time.Now().Add(replayWindow))
+			continue
+		}
+		attempt++
+
+		delay := retry.Next()
+		s.emit(Event{State: StateReconnecting, Attempt: attempt, RetryIn: delay, Err: err})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.emit(Event{State: StateStopped})
+			return nil
+		case <-timer.C:
+		}
+
+		s.dedupe.startReplay(time.Now().Add(replayWindow))
+	}
+}
+
+// connect открывает поток и ждёт его завершения. connected сообщает,
+// успел ли поток начать работать. resumed поток считается подключённым
+// сразу, о нём не сообщается повторно
+func (s *Supervisor) connect(ctx context.Context, attempt int, resumed bool) (connected bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	connected = resumed
+
+	activity := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.client.ConnectChat(ctx, s.chatID, s.username, func(msg *chat.Message) {
+			// Ждём, пока цикл ниже учтёт сообщение, чтобы событие о подключении
+			// было показано раньше первого сообщения
+			select {
+			case activity <- struct{}{}:
+			case <-ctx.Done():
+			}
+
+			if s.dedupe.accept(msg) {
+				s.handler(msg)
+			}
+		})
+	}()
+
+	grace := time.NewTimer(connectGrace)
+	defer grace.Stop()
+
+	// Без StallTimeout канал stalled остаётся nil и никогда не срабатывает
+	var (
+		timeout = s.cfg.StallTimeout()
+		stall   *time.Timer
+		stalled <-chan time.Time
+	)
+	if timeout > 0 {
+		stall = time.NewTimer(timeout)
+		defer stall.Stop()
+		stalled = stall.C
+	}
+
+	for {
+		select {
+		case err := <-done:
+			return connected, err
+		case <-grace.C:
+			connected = s.markConnected(connected, attempt)
+		case <-activity:
+			connected = s.markConnected(connected, attempt)
+			if stall != nil {
+				resetTimer(stall, timeout)
+			}
+		case <-stalled:
+			cancel()
+			<-done
+			return connected, ErrStalled
+		}
+	}
+}
+
+func (s *Supervisor) markConnected(connected bool, attempt int) bool {
+	if !connected {
+		s.emit(Event{State: StateConnected, Attempt: attempt})
+	}
+
+	return true
+}
+
+func (s *Supervisor) emit(e Event) {
+	if s.onEvent != nil {
+		s.onEvent(e)
+	}
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// retryable сообщает, может ли переподключение помочь. Поток, закрытый
+// сервером без ошибки, тоже переподключается
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, ErrStalled) {
+		return true
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Aborted, codes.Internal, codes.Unknown, codes.Canceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Describe описывает событие для пользователя
+func Describe(e Event) string {
+	switch {
+	case e.State == StateConnected && e.Attempt > 0:
+		return "reconnected"
+	case e.State == StateReconnecting && e.Err != nil:
+		return fmt.Sprintf("connection lost (%v), reconnecting in %s (attempt %d)…", errorText(e.Err), e.RetryIn.Round(100*time.Millisecond), e.Attempt)
+	case e.State == StateReconnecting:
+		return fmt.Sprintf("stream closed by server, reconnecting in %s (attempt %d)…", e.RetryIn.Round(100*time.Millisecond), e.Attempt)
+	case e.State == StateStopped && e.Err != nil:
+		return fmt.Sprintf("disconnected: %v", errorText(e.Err))
+	default:
+		return e.State.String()
+	}
+}
+
+func errorText(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return st.Message()
+	}
+
+	return err.Error()
+}
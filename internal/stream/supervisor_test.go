@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+)
+
+type testConfig struct {
+	stallTimeout time.Duration
+}
+
+func (c testConfig) ReconnectMinDelay() time.Duration { return time.Millisecond }
+func (c testConfig) ReconnectMaxDelay() time.Duration { return 10 * time.Millisecond }
+func (c testConfig) StallTimeout() time.Duration      { return c.stallTimeout }
+
+// quietClient на каждый поток присылает одно сообщение и замолкает
+type quietClient struct {
+	mu      sync.Mutex
+	streams int
+	opened  chan int
+}
+
+func (c *quietClient) Create(context.Context, []string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (c *quietClient) Delete(context.Context, string) error {
+	return errors.New("not implemented")
+}
+
+func (c *quietClient) SendMessage(context.Context, *chat.Message) error {
+	return errors.New("not implemented")
+}
+
+func (c *quietClient) ConnectChat(ctx context.Context, chatID, _ string, handler func(*chat.Message)) error {
+	c.mu.Lock()
+	c.streams++
+	n := c.streams
+	c.mu.Unlock()
+
+	handler(&chat.Message{ChatID: chatID, Username: "alice", Text: fmt.Sprintf("message %d", n)})
+	select {
+	case c.opened <- n:
+	case <-ctx.Done():
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSupervisorReopensStalledStreamQuietly(t *testing.T) {
+	client := &quietClient{opened: make(chan int)}
+
+	var (
+		mu       sync.Mutex
+		events   []Event
+		messages int
+	)
+	s := NewSupervisor(client, testConfig{stallTimeout: 20 * time.Millisecond}, "1", "bob",
+		func(*chat.Message) {
+			mu.Lock()
+			messages++
+			mu.Unlock()
+		},
+		func(e Event) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	for n := 0; n < 3; {
+		select {
+		case n = <-client.opened:
+		case <-time.After(5 * time.Second):
+			t.Fatal("stalled stream was not reopened")
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var states []State
+	for _, e := range events {
+		states = append(states, e.State)
+	}
+	if want := []State{StateConnecting, StateConnected, StateStopped}; fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Fatalf("events %v, want %v: reopening a quiet stream must not be reported", states, want)
+	}
+	if messages < 3 {
+		t.Fatalf("handled %d messages, want every reopened stream's message", messages)
+	}
+}
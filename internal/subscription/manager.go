@@ -0,0 +1,240 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/clients"
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"github.com/Mobo140/chat-cli/internal/stream"
+)
+
+var (
+	ErrAlreadySubscribed = errors.New("already subscribed to this chat")
+	ErrNotSubscribed     = errors.New("not subscribed to this chat")
+)
+
+// Info состояние подписки на чат
+type Info struct {
+	ChatID string
+	// Label псевдоним чата или его ID для вывода
+	Label string
+	// Account аккаунт, от имени которого открыт поток
+	Account      string
+	Username     string
+	State        stream.State
+	Messages     int
+	LastActivity time.Time
+	Muted        bool
+	Err          error
+}
+
+// Handler получает сообщения и события подписки
+type Handler interface {
+	Message(info Info, msg *chat.Message)
+	Event(info Info, e stream.Event)
+}
+
+// Manager держит несколько потоков ConnectChat одновременно, каждый в своей горутине.
+// Один чат может быть подписан от имени нескольких аккаунтов
+type Manager struct {
+	client clients.ChatServiceClient
+	cfg    config.StreamConfig
+
+	mu   sync.Mutex
+	subs map[key]*subscription
+	wg   sync.WaitGroup
+}
+
+// key подписка определяется аккаунтом и чатом
+type key struct {
+	account string
+	chatID  string
+}
+
+type subscription struct {
+	info    Info
+	handler Handler
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func NewManager(client clients.ChatServiceClient, cfg config.StreamConfig) *Manager {
+	return &Manager{
+		client: client,
+		cfg:    cfg,
+		subs:   make(map[key]*subscription),
+	}
+}
+
+// Subscribe открывает поток чата от имени аккаунта. Остановленная подписка
+// аккаунта на тот же чат запускается заново
+func (m *Manager) Subscribe(chatID, label, acc, username string, handler Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{account: acc, chatID: chatID}
+
+	if sub, ok := m.subs[k]; ok {
+		select {
+		case <-sub.done:
+		default:
+			return ErrAlreadySubscribed
+		}
+	}
+
+	ctx, cancel := context.WithCancel(account.WithUsername(context.Background(), acc))
+
+	sub := &subscription{
+		info: Info{
+			ChatID:   chatID,
+			Label:    label,
+			Account:  acc,
+			Username: username,
+			State:    stream.StateConnecting,
+		},
+		handler: handler,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	m.subs[k] = sub
+
+	supervisor := stream.NewSupervisor(m.client, m.cfg, chatID, username,
+		func(msg *chat.Message) { m.message(sub, msg) },
+		func(e stream.Event) { m.event(sub, e) },
+	)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer close(sub.done)
+
+		supervisor.Run(ctx)
+	}()
+
+	return nil
+}
+
+// Unsubscribe закрывает поток аккаунта и дожидается его остановки
+func (m *Manager) Unsubscribe(acc, chatID string) error {
+	k := key{account: acc, chatID: chatID}
+
+	m.mu.Lock()
+	sub, ok := m.subs[k]
+	delete(m.subs, k)
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrNotSubscribed
+	}
+
+	sub.cancel()
+	<-sub.done
+
+	return nil
+}
+
+// UnsubscribeAccount закрывает все потоки аккаунта, например при выходе из него,
+// и возвращает их состояние на момент остановки
+func (m *Manager) UnsubscribeAccount(acc string) []Info {
+	var stopped []*subscription
+
+	m.mu.Lock()
+	for k, sub := range m.subs {
+		if k.account == acc {
+			stopped = append(stopped, sub)
+			delete(m.subs, k)
+		}
+	}
+	m.mu.Unlock()
+
+	infos := make([]Info, 0, len(stopped))
+	for _, sub := range stopped {
+		sub.cancel()
+		<-sub.done
+
+		infos = append(infos, sub.info)
+	}
+
+	return infos
+}
+
+// SetMuted включает или выключает вывод сообщений чата, сообщения продолжают приниматься
+func (m *Manager) SetMuted(acc, chatID string, muted bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub, ok := m.subs[key{account: acc, chatID: chatID}]
+	if !ok {
+		return ErrNotSubscribed
+	}
+	sub.info.Muted = muted
+
+	return nil
+}
+
+// List возвращает подписки, отсортированные по метке и аккаунту
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Info, 0, len(m.subs))
+	for _, sub := range m.subs {
+		list = append(list, sub.info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Label != list[j].Label {
+			return list[i].Label < list[j].Label
+		}
+		return list[i].Account < list[j].Account
+	})
+
+	return list
+}
+
+// Wait дожидается остановки всех потоков, например после неустранимых ошибок.
+// Не должен вызываться одновременно с Subscribe
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Close закрывает все потоки и дожидается их остановки
+func (m *Manager) Close() {
+	m.mu.Lock()
+	for k, sub := range m.subs {
+		sub.cancel()
+		delete(m.subs, k)
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+func (m *Manager) message(sub *subscription, msg *chat.Message) {
+	m.mu.Lock()
+	sub.info.Messages++
+	sub.info.LastActivity = time.Now()
+	info := sub.info
+	m.mu.Unlock()
+
+	sub.handler.Message(info, msg)
+}
+
+func (m *Manager) event(sub *subscription, e stream.Event) {
+	m.mu.Lock()
+	sub.info.State = e.State
+	if e.State == stream.StateConnected {
+		sub.info.Err = nil
+	} else if e.Err != nil {
+		sub.info.Err = e.Err
+	}
+	info := sub.info
+	m.mu.Unlock()
+
+	sub.handler.Event(info, e)
+}
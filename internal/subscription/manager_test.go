@@ -0,0 +1,68 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/clients/chat"
+	"github.com/Mobo140/chat-cli/internal/stream"
+)
+
+type idleClient struct{}
+
+func (idleClient) Create(context.Context, []string) (string, error) { return "", nil }
+func (idleClient) Delete(context.Context, string) error             { return nil }
+func (idleClient) SendMessage(context.Context, *chat.Message) error { return nil }
+
+func (idleClient) ConnectChat(ctx context.Context, _, _ string, _ func(*chat.Message)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type streamConfig struct{}
+
+func (streamConfig) ReconnectMinDelay() time.Duration { return 10 * time.Millisecond }
+func (streamConfig) ReconnectMaxDelay() time.Duration { return 100 * time.Millisecond }
+func (streamConfig) StallTimeout() time.Duration      { return 0 }
+
+type nopHandler struct{}
+
+func (nopHandler) Message(Info, *chat.Message) {}
+func (nopHandler) Event(Info, stream.Event)    {}
+
+func TestSubscriptionsAreKeyedByAccount(t *testing.T) {
+	m := NewManager(idleClient{}, streamConfig{})
+	defer m.Close()
+
+	for _, acc := range []string{"alice", "bob"} {
+		if err := m.Subscribe("7", "team", acc, acc, nopHandler{}); err != nil {
+			t.Fatalf("subscribe %s: %v", acc, err)
+		}
+	}
+	if err := m.Subscribe("7", "team", "alice", "alice", nopHandler{}); !errors.Is(err, ErrAlreadySubscribed) {
+		t.Fatalf("second subscribe of alice = %v, want ErrAlreadySubscribed", err)
+	}
+
+	if err := m.SetMuted("bob", "7", true); err != nil {
+		t.Fatal(err)
+	}
+
+	list := m.List()
+	if len(list) != 2 || list[0].Account != "alice" || list[0].Muted || list[1].Account != "bob" || !list[1].Muted {
+		t.Fatalf("List() = %+v, want alice unmuted and bob muted", list)
+	}
+
+	stopped := m.UnsubscribeAccount("alice")
+	if len(stopped) != 1 || stopped[0].Account != "alice" {
+		t.Fatalf("UnsubscribeAccount(alice) = %+v", stopped)
+	}
+
+	if err := m.Unsubscribe("alice", "7"); !errors.Is(err, ErrNotSubscribed) {
+		t.Fatalf("unsubscribe alice = %v, want ErrNotSubscribed", err)
+	}
+	if err := m.Unsubscribe("bob", "7"); err != nil {
+		t.Fatalf("unsubscribe bob: %v", err)
+	}
+}
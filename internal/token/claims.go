@@ -0,0 +1,47 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrMalformedToken = errors.New("malformed token")
+
+// Claims полезная нагрузка JWT, выдаваемого сервисом auth
+type Claims struct {
+	ExpiresAt int64  `json:"exp"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+}
+
+// ParseClaims декодирует полезную нагрузку токена без проверки подписи:
+// подпись проверяет сервер, клиенту нужны только сроки и данные пользователя
+func ParseClaims(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	return &claims, nil
+}
+
+func (c *Claims) ExpiresAtTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+func (c *Claims) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAtTime())
+}
@@ -0,0 +1,22 @@
+package token
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IsRejected сообщает, что сервер ответил на запрос и отклонил токен,
+// в отличие от временных сбоев сети, после которых стоит повторить попытку
+func IsRejected(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return false
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.ResourceExhausted, codes.Aborted:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,479 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/backoff"
+	"github.com/Mobo140/chat-cli/internal/clients"
+	"github.com/Mobo140/chat-cli/internal/config"
+	"github.com/Mobo140/chat-cli/internal/credentials"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	requestTimeout       = 20 * time.Second
+	minRefreshInterval   = 10 * time.Second
+	followerPollInterval = 5 * time.Second
+	minRetryDelay        = time.Second
+	maxRetryDelay        = 2 * time.Minute
+	// sessionCacheMinAge недавно изменённый файл сессии перечитывается всегда:
+	// при грубом времени изменения две записи подряд могут его не поменять
+	sessionCacheMinAge = 2 * time.Second
+)
+
+var (
+	ErrRefreshTokenExpired = errors.New("refresh token expired, please login again")
+	ErrNotLoggedIn         = errors.New("not logged in, please login first")
+	// ErrInvalidRefreshToken refresh token не разбирается, повтор запроса не поможет
+	ErrInvalidRefreshToken = errors.New("invalid refresh token, please login again")
+)
+
+// Manager поддерживает токены активного пользователя в актуальном состоянии
+type Manager interface {
+	// Run обслуживает токены до отмены ctx
+	Run(ctx context.Context)
+	// Start (пере)запускает обслуживание токенов пользователя, вызывается
+	// при входе и переключении аккаунта
+	Start(username string)
+	// Stop останавливает обслуживание токенов
+	Stop()
+	// AccessToken возвращает текущий access token аккаунта из контекста
+	// или активного аккаунта
+	AccessToken(ctx context.Context) (string, error)
+	// RefreshAccessToken получает новый access token по refresh token
+	RefreshAccessToken(ctx context.Context) (string, error)
+	// Login создаёт сессию по паролю
+	Login(ctx context.Context, username, password string) error
+	// Import создаёт сессию из готового refresh token и возвращает имя пользователя из его claims
+	Import(ctx context.Context, refreshToken string) (string, error)
+	// Relogin выполняет повторный вход по сохранённым учётным данным
+	Relogin(ctx context.Context, username string) error
+}
+
+var _ Manager = (*manager)(nil)
+
+type manager struct {
+	authClient   clients.AuthServiceClient
+	sessionStore session.Store
+	accountStore account.Store
+	credentials  credentials.Store
+	config       config.TokenConfig
+
+	switchCh chan string
+	cache    sessionCache
+}
+
+// sessionCache последняя прочитанная сессия и время изменения её файла
+type sessionCache struct {
+	mu       sync.Mutex
+	username string
+	modTime  time.Time
+	session  model.Session
+}
+
+func NewManager(
+	authClient clients.AuthServiceClient,
+	sessionStore session.Store,
+	accountStore account.Store,
+	credentialsStore credentials.Store,
+	cfg config.TokenConfig,
+) *manager {
+	return &manager{
+		authClient:   authClient,
+		sessionStore: sessionStore,
+		accountStore: accountStore,
+		credentials:  credentialsStore,
+		config:       cfg,
+		switchCh:     make(chan string, 1),
+	}
+}
+
+func (m *manager) Start(username string) {
+	m.switchTo(username)
+}
+
+func (m *manager) Stop() {
+	m.switchTo("")
+}
+
+// switchTo заменяет ещё не обработанную команду, если она есть
+func (m *manager) switchTo(username string) {
+	for {
+		select {
+		case m.switchCh <- username:
+			return
+		default:
+		}
+
+		select {
+		case <-m.switchCh:
+		default:
+		}
+	}
+}
+
+func (m *manager) Run(ctx context.Context) {
+	var (
+		username string
+		retry    = backoff.New(minRetryDelay, maxRetryDelay)
+		timer    = time.NewTimer(0)
+		lease    = &refresherLease{}
+	)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+	defer lease.release()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case username = <-m.switchCh:
+			lease.release()
+			retry.Reset()
+			resetTimer(timer, 0)
+
+			if username == "" {
+				timer.Stop()
+				logger.Debug("Token maintenance stopped")
+			}
+			continue
+		case <-timer.C:
+		}
+
+		// Токены пользователя обновляет только один из запущенных процессов,
+		// остальные читают обновлённые токены из файла сессии
+		if !lease.acquire(m.sessionStore, username) {
+			resetTimer(timer, followerPollInterval)
+			continue
+		}
+
+		next, err := m.maintain(ctx, username)
+		if loginRequired(err) {
+			if reloginErr := m.Relogin(ctx, username); reloginErr == nil {
+				logger.Info("Logged in again with saved credentials", zap.String("username", username))
+				resetTimer(timer, 0)
+				continue
+			} else if !errors.Is(reloginErr, credentials.ErrNotFound) {
+				logger.Error("failed to login with saved credentials", zap.Error(reloginErr))
+			}
+		}
+
+		switch {
+		case loginRequired(err), errors.Is(err, session.ErrNotFound):
+			logger.Warn("Token maintenance stopped", zap.String("username", username), zap.Error(err))
+			continue
+		case err != nil:
+			next = retry.Next()
+			logger.Error("failed to refresh tokens",
+				zap.String("username", username),
+				zap.Duration("retry_in", next),
+				zap.Error(err))
+		default:
+			retry.Reset()
+			logger.Debug("Next token refresh scheduled",
+				zap.String("username", username),
+				zap.Duration("in", next))
+		}
+
+		resetTimer(timer, next)
+	}
+}
+
+// loginRequired сообщает, что токены сессии уже не обновить и нужен новый вход
+func loginRequired(err error) bool {
+	return errors.Is(err, ErrRefreshTokenExpired) || errors.Is(err, ErrInvalidRefreshToken) || IsRejected(err)
+}
+
+func (m *manager) AccessToken(ctx context.Context) (string, error) {
+	s, err := m.currentSession(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return s.AccessToken, nil
+}
+
+func (m *manager) RefreshAccessToken(ctx context.Context) (string, error) {
+	s, err := m.currentSession(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	accessToken, err := m.authClient.GetAccessToken(ctx, s.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.setTokens(s.Username, "", accessToken); err != nil {
+		return "", err
+	}
+
+	logger.Info("Access token updated", zap.String("username", s.Username))
+
+	return accessToken, nil
+}
+
+func (m *manager) Login(ctx context.Context, username, password string) error {
+	refreshToken, err := m.authClient.Login(ctx, username, password)
+	if err != nil {
+		return err
+	}
+
+	return m.createSession(ctx, username, refreshToken)
+}
+
+func (m *manager) Import(ctx context.Context, refreshToken string) (string, error) {
+	claims, err := ParseClaims(refreshToken)
+	if err != nil {
+		return "", fmt.Errorf("invalid refresh token: %w", err)
+	}
+	if claims.Username == "" {
+		return "", errors.New("refresh token has no username claim")
+	}
+	if claims.Expired(time.Now()) {
+		return "", ErrRefreshTokenExpired
+	}
+
+	return claims.Username, m.createSession(ctx, claims.Username, refreshToken)
+}
+
+func (m *manager) Relogin(ctx context.Context, username string) error {
+	profile, err := m.credentials.ByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	password, err := profile.Password()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return m.Login(ctx, username, password)
+}
+
+func (m *manager) createSession(ctx context.Context, username, refreshToken string) error {
+	accessToken, err := m.authClient.GetAccessToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return m.sessionStore.Save(&model.Session{
+		Username:     username,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		RefreshedAt:  time.Now(),
+	})
+}
+
+func (m *manager) currentSession(ctx context.Context) (*model.Session, error) {
+	username, err := account.Current(ctx, m.accountStore)
+	if errors.Is(err, account.ErrNoActiveAccount) {
+		return nil, ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := m.loadSession(username)
+	if errors.Is(err, session.ErrNotFound) {
+		return nil, ErrNotLoggedIn
+	}
+
+	return s, err
+}
+
+// loadSession читает файл сессии, только если он изменился с прошлого чтения:
+// так процессы, которые сами не обновляют токены, подхватывают токены,
+// обновлённые другим процессом, не перечитывая файл на каждый запрос
+func (m *manager) loadSession(username string) (*model.Session, error) {
+	elector, ok := m.sessionStore.(session.Elector)
+	if !ok {
+		return m.sessionStore.Load(username)
+	}
+
+	modTime, err := elector.ModTime(username)
+	if err != nil {
+		return nil, err
+	}
+
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+
+	cached := m.cache.username == username
+	if cached && m.cache.modTime.Equal(modTime) && time.Since(modTime) > sessionCacheMinAge {
+		s := m.cache.session
+		return &s, nil
+	}
+
+	s, err := m.sessionStore.Load(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached && s.RefreshToken != m.cache.session.RefreshToken {
+		logger.Debug("Picked up rotated tokens from the session file", zap.String("username", username))
+	}
+	m.cache.username = username
+	m.cache.modTime = modTime
+	m.cache.session = *s
+
+	return s, nil
+}
+
+// maintain обновляет токены, срок которых подходит к концу, и возвращает
+// время до следующего обновления
+func (m *manager) maintain(ctx context.Context, username string) (time.Duration, error) {
+	s, err := m.sessionStore.Load(username)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshClaims, err := ParseClaims(s.RefreshToken)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+	}
+
+	now := time.Now()
+	if refreshClaims.Expired(now) {
+		return 0, ErrRefreshTokenExpired
+	}
+
+	if !now.Before(refreshClaims.ExpiresAtTime().Add(-m.config.RefreshTokenMargin())) {
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		refreshToken, err := m.authClient.GetRefreshToken(ctx, s.RefreshToken)
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+
+		refreshClaims, err = ParseClaims(refreshToken)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+		}
+
+		s.RefreshToken = refreshToken
+		if err := m.setTokens(username, refreshToken, ""); err != nil {
+			return 0, err
+		}
+
+		logger.Info("Refresh token updated", zap.String("username", username))
+	}
+
+	accessClaims, err := ParseClaims(s.AccessToken)
+	if err != nil || !now.Before(accessClaims.ExpiresAtTime().Add(-m.config.AccessTokenMargin())) {
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		accessToken, err := m.authClient.GetAccessToken(ctx, s.RefreshToken)
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+
+		accessClaims, err = ParseClaims(accessToken)
+		if err != nil {
+			return 0, fmt.Errorf("invalid access token: %w", err)
+		}
+
+		if err := m.setTokens(username, "", accessToken); err != nil {
+			return 0, err
+		}
+
+		logger.Info("Access token updated", zap.String("username", username))
+	}
+
+	next := earliest(
+		accessClaims.ExpiresAtTime().Add(-m.config.AccessTokenMargin()),
+		refreshClaims.ExpiresAtTime().Add(-m.config.RefreshTokenMargin()),
+	)
+
+	wait := time.Until(next)
+	if wait < minRefreshInterval {
+		wait = minRefreshInterval
+	}
+
+	return wait, nil
+}
+
+// setTokens обновляет только переданные токены, не затирая изменения
+// других процессов
+func (m *manager) setTokens(username, refreshToken, accessToken string) error {
+	return m.sessionStore.Update(username, func(s *model.Session) error {
+		if refreshToken != "" {
+			s.RefreshToken = refreshToken
+		}
+		if accessToken != "" {
+			s.AccessToken = accessToken
+		}
+		s.RefreshedAt = time.Now()
+
+		return nil
+	})
+}
+
+// refresherLease роль процесса, обновляющего токены пользователя
+type refresherLease struct {
+	unlock func()
+}
+
+func (l *refresherLease) acquire(store session.Store, username string) bool {
+	if l.unlock != nil {
+		return true
+	}
+
+	elector, ok := store.(session.Elector)
+	if !ok {
+		l.unlock = func() {}
+		return true
+	}
+
+	release, ok, err := elector.TryAcquireRefresher(username)
+	if err != nil {
+		logger.Error("failed to acquire token refresher lock", zap.Error(err))
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	logger.Debug("This process refreshes tokens", zap.String("username", username))
+	l.unlock = release
+
+	return true
+}
+
+func (l *refresherLease) release() {
+	if l.unlock != nil {
+		l.unlock()
+	}
+	*l = refresherLease{}
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
@@ -0,0 +1,341 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mobo140/chat-cli/internal/account"
+	"github.com/Mobo140/chat-cli/internal/credentials"
+	"github.com/Mobo140/chat-cli/internal/model"
+	"github.com/Mobo140/chat-cli/internal/session"
+	"github.com/Mobo140/platform_common/pkg/logger"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errUnavailable = status.Error(codes.Unavailable, "auth is down")
+
+// testToken выпускает неподписанный JWT, который принимает ParseClaims
+func testToken(t *testing.T, username string, ttl time.Duration) string {
+	t.Helper()
+
+	payload, err := json.Marshal(Claims{Username: username, ExpiresAt: time.Now().Add(ttl).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+// fakeAuthClient пускает пользователя с паролем password и считает запросы
+type fakeAuthClient struct {
+	t        *testing.T
+	password string
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *fakeAuthClient) call() {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+}
+
+func (c *fakeAuthClient) Login(_ context.Context, username, password string) (string, error) {
+	c.call()
+	if c.password == "" || password != c.password {
+		return "", status.Error(codes.Unauthenticated, "wrong password")
+	}
+
+	return testToken(c.t, username, 24*time.Hour), nil
+}
+
+func (c *fakeAuthClient) GetAccessToken(_ context.Context, refreshToken string) (string, error) {
+	c.call()
+	claims, err := ParseClaims(refreshToken)
+	if err != nil {
+		return "", status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return testToken(c.t, claims.Username, time.Hour), nil
+}
+
+func (c *fakeAuthClient) GetRefreshToken(context.Context, string) (string, error) {
+	c.call()
+	return "", errUnavailable
+}
+
+func (c *fakeAuthClient) CheckAccess(context.Context, string, string) error {
+	c.call()
+	return errUnavailable
+}
+
+type testConfig struct{}
+
+func (testConfig) AccessTokenMargin() time.Duration  { return time.Minute }
+func (testConfig) RefreshTokenMargin() time.Duration { return time.Hour }
+
+type managerTest struct {
+	auth     *fakeAuthClient
+	sessions session.Store
+	logs     *observer.ObservedLogs
+	manager  *manager
+}
+
+// newManagerTest готовит менеджер с сохранённой сессией bob. Непустой password
+// сохраняется как учётные данные bob для повторного входа
+func newManagerTest(t *testing.T, refreshToken, password string) *managerTest {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.Init(core)
+
+	dir := t.TempDir()
+	credentialsPath := filepath.Join(dir, "credentials.json")
+	if password != "" {
+		passwordPath := filepath.Join(dir, "password")
+		if err := os.WriteFile(passwordPath, []byte(password+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := json.Marshal(map[string]any{
+			"profiles": map[string]credentials.Profile{
+				"bob": {Username: "bob", PasswordFile: passwordPath},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(credentialsPath, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions := session.NewMemoryStore()
+	err := sessions.Save(&model.Session{
+		Username:     "bob",
+		AccessToken:  testToken(t, "bob", time.Hour),
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := &fakeAuthClient{t: t, password: password}
+
+	return &managerTest{
+		auth:     auth,
+		sessions: sessions,
+		logs:     logs,
+		manager: NewManager(
+			auth,
+			sessions,
+			account.NewFileStore(filepath.Join(dir, "account")),
+			credentials.NewFileStore(credentialsPath),
+			testConfig{},
+		),
+	}
+}
+
+// run обслуживает токены bob, пока cond не выполнится
+func (mt *managerTest) run(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		mt.manager.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	mt.manager.Start("bob")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// stopped сообщает, что обслуживание токенов остановлено с ошибкой target
+func (mt *managerTest) stopped(target error) func() bool {
+	return func() bool {
+		for _, entry := range mt.logs.FilterMessage("Token maintenance stopped").All() {
+			if err, ok := entry.ContextMap()["error"].(string); ok && strings.HasPrefix(err, target.Error()) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func TestRunStopsOnMalformedRefreshToken(t *testing.T) {
+	mt := newManagerTest(t, "not a token", "")
+
+	mt.run(t, mt.stopped(ErrInvalidRefreshToken))
+
+	if n := mt.logs.FilterMessage("failed to refresh tokens").Len(); n != 0 {
+		t.Fatalf("malformed refresh token was retried %d time(s)", n)
+	}
+
+	mt.auth.mu.Lock()
+	defer mt.auth.mu.Unlock()
+	if mt.auth.calls != 0 {
+		t.Fatalf("auth was called %d time(s) with a malformed refresh token", mt.auth.calls)
+	}
+}
+
+func TestRunLogsInAgainOnMalformedRefreshToken(t *testing.T) {
+	mt := newManagerTest(t, "not a token", "secret")
+
+	mt.run(t, func() bool {
+		return mt.logs.FilterMessage("Logged in again with saved credentials").Len() > 0
+	})
+
+	s, err := mt.sessions.Load("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseClaims(s.RefreshToken); err != nil {
+		t.Fatalf("session still has a malformed refresh token after login: %v", err)
+	}
+}
+
+// newFileManager создаёт менеджер, который, как отдельный процесс, открывает
+// общий файл сессий base со своим хранилищем
+func newFileManager(t *testing.T, auth *fakeAuthClient, base string) (*manager, session.Store) {
+	t.Helper()
+
+	sessions := session.NewFileStore(base, session.NewPlainCodec())
+	accounts := account.NewFileStore(filepath.Join(t.TempDir(), "account"))
+	if err := accounts.SetActive("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewManager(auth, sessions, accounts, credentials.NewFileStore(""), testConfig{}), sessions
+}
+
+// saveAt сохраняет сессию bob и выставляет файлу время изменения
+func saveAt(t *testing.T, sessions session.Store, accessToken string, modTime time.Time) {
+	t.Helper()
+
+	err := sessions.Save(&model.Session{Username: "bob", AccessToken: accessToken, RefreshToken: "refresh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(sessions.(session.Locator).Path("bob"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccessTokenPicksUpTokensRotatedByAnotherProcess(t *testing.T) {
+	logger.Init(zapcore.NewNopCore())
+
+	base := filepath.Join(t.TempDir(), "session")
+	m, _ := newFileManager(t, &fakeAuthClient{t: t}, base)
+	other := session.NewFileStore(base, session.NewPlainCodec())
+
+	modTime := time.Now().Add(-time.Hour)
+	saveAt(t, other, "first", modTime)
+
+	if got, err := m.AccessToken(context.Background()); err != nil || got != "first" {
+		t.Fatalf("AccessToken = %q, %v, want first", got, err)
+	}
+
+	// Пока время изменения то же, файл не перечитывается
+	saveAt(t, other, "unseen", modTime)
+	if got, err := m.AccessToken(context.Background()); err != nil || got != "first" {
+		t.Fatalf("AccessToken with unchanged mtime = %q, %v, want first", got, err)
+	}
+
+	saveAt(t, other, "rotated", modTime.Add(time.Minute))
+	if got, err := m.AccessToken(context.Background()); err != nil || got != "rotated" {
+		t.Fatalf("AccessToken after rotation = %q, %v, want rotated", got, err)
+	}
+
+	// Только что изменённый файл перечитывается, даже если время не сдвинулось
+	now := time.Now()
+	saveAt(t, other, "recent", now)
+	if _, err := m.AccessToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	saveAt(t, other, "recent again", now)
+	if got, err := m.AccessToken(context.Background()); err != nil || got != "recent again" {
+		t.Fatalf("AccessToken after a recent write = %q, %v, want recent again", got, err)
+	}
+
+	if err := other.Delete("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.AccessToken(context.Background()); !errors.Is(err, ErrNotLoggedIn) {
+		t.Fatalf("AccessToken after logout error = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestOnlyOneProcessRefreshesTokens(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.Init(core)
+
+	base := filepath.Join(t.TempDir(), "session")
+	auth := &fakeAuthClient{t: t}
+	first, sessions := newFileManager(t, auth, base)
+	second, _ := newFileManager(t, auth, base)
+
+	// Access token истекает раньше запаса, его нужно обновить сразу
+	err := sessions.Save(&model.Session{
+		Username:     "bob",
+		AccessToken:  testToken(t, "bob", time.Second),
+		RefreshToken: testToken(t, "bob", 24*time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, m := range []*manager{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Run(ctx)
+		}()
+		m.Start("bob")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage("Access token updated").Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("access token was not refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Второй процесс успел бы обновить токены в это время, если бы не ждал роли
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	if n := logs.FilterMessage("Access token updated").Len(); n != 1 {
+		t.Fatalf("access token refreshed %d times, want once by the elected process", n)
+	}
+	if n := logs.FilterMessage("This process refreshes tokens").Len(); n != 1 {
+		t.Fatalf("%d processes refreshed tokens, want 1", n)
+	}
+}